@@ -0,0 +1,265 @@
+package badgerutils
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/badger/protos"
+)
+
+// BackupManifest describes a backup file. It is stored next to the backup file as a JSON sidecar.
+type BackupManifest struct {
+	// Since is the version the backup was taken from. A full backup has a Since of 0.
+	Since uint64 `json:"since"`
+	// Version is the version returned by the backup which is used as Since for the next incremental backup.
+	Version uint64 `json:"version"`
+	// Keys is the number of key versions in the backup.
+	Keys int `json:"keys"`
+	// Checksum is the hex encoded SHA-256 of the backup file.
+	Checksum  string    `json:"checksum"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ManifestPath returns the path of the manifest sidecar for a backup file.
+func ManifestPath(backupPath string) string {
+	return backupPath + ".manifest.json"
+}
+
+// Backup writes a backup of all key versions in the Badger at dir at or after version since to w.
+// It returns the version to pass as since to take the next incremental backup.
+func Backup(dir string, w io.Writer, since uint64) (uint64, error) {
+	db, err := openDB(dir)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	return db.Backup(w, since)
+}
+
+// Restore loads a backup from r into the Badger at dir.
+func Restore(dir string, r io.Reader) error {
+	if mkdirErr := os.MkdirAll(dir, os.ModePerm); mkdirErr != nil {
+		return mkdirErr
+	}
+
+	db, err := openDB(dir)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Load(r)
+}
+
+// maxFrameSize bounds the frames of readFrames. A frame holds one key/value, whose key is at most 64KB and whose
+// value is bounded by the 1GB default value log file size.
+const maxFrameSize = 1<<30 + 1<<20
+
+// readFrames reads frames prefixed with their little-endian uint64 length, as written by Badger's Backup, and
+// calls fn for each. The frame is only valid until fn returns.
+func readFrames(r io.Reader, fn func([]byte) error) error {
+	br := bufio.NewReaderSize(r, 16<<10)
+	buf := make([]byte, 1<<10)
+	for {
+		var size uint64
+		if err := binary.Read(br, binary.LittleEndian, &size); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if size > maxFrameSize {
+			return fmt.Errorf("frame of %v bytes is larger than %v bytes", size, uint64(maxFrameSize))
+		}
+		if uint64(cap(buf)) < size {
+			buf = make([]byte, size)
+		}
+		if _, err := io.ReadFull(br, buf[:size]); err != nil {
+			return err
+		}
+		if err := fn(buf[:size]); err != nil {
+			return err
+		}
+	}
+}
+
+// readBackup reads the length-prefixed protobuf KVPair entries written by Badger's Backup and calls fn for each.
+func readBackup(r io.Reader, fn func(*protos.KVPair) error) error {
+	return readFrames(r, func(frame []byte) error {
+		kv := &protos.KVPair{}
+		if err := kv.Unmarshal(frame); err != nil {
+			return err
+		}
+		return fn(kv)
+	})
+}
+
+// fileChecksum returns the hex encoded SHA-256 and the number of entries of a backup file.
+func fileChecksum(backupPath string) (string, int, error) {
+	f, err := os.Open(backupPath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	keys := 0
+	err = readBackup(io.TeeReader(f, h), func(*protos.KVPair) error {
+		keys++
+		return nil
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), keys, nil
+}
+
+// ReadManifest reads the manifest sidecar of a backup file.
+func ReadManifest(backupPath string) (*BackupManifest, error) {
+	b, err := ioutil.ReadFile(ManifestPath(backupPath))
+	if err != nil {
+		return nil, err
+	}
+	manifest := &BackupManifest{}
+	if err := json.Unmarshal(b, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func writeManifest(backupPath string, manifest *BackupManifest) error {
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(ManifestPath(backupPath), b, 0644)
+}
+
+// BackupFile writes a backup of the Badger at dir at or after version since to backupPath along with its manifest
+// sidecar.
+// Pass 0 as since for a full backup or the Version of the previous manifest for an incremental backup.
+func BackupFile(dir string, backupPath string, since uint64) (*BackupManifest, error) {
+	if mkdirErr := os.MkdirAll(path.Dir(backupPath), os.ModePerm); mkdirErr != nil {
+		return nil, mkdirErr
+	}
+
+	f, err := os.Create(backupPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	start := time.Now()
+
+	bw := bufio.NewWriter(f)
+	version, err := Backup(dir, bw, since)
+	if err != nil {
+		return nil, err
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	if err := f.Sync(); err != nil {
+		return nil, err
+	}
+
+	checksum, keys, err := fileChecksum(backupPath)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &BackupManifest{
+		Since:     since,
+		Version:   version,
+		Keys:      keys,
+		Checksum:  checksum,
+		Timestamp: start.UTC(),
+	}
+	if err := writeManifest(backupPath, manifest); err != nil {
+		return nil, err
+	}
+
+	DefaultLogger.Log(LevelInfo, "Backed up keys", "keys", keys, "since", since, "elapsed", time.Since(start))
+	return manifest, nil
+}
+
+// RestoreFiles restores a chain of backup files into the Badger at dir.
+// The backups are ordered by their manifests and must start with a full backup followed by incrementals each
+// taken from the version of the previous one. Every file is verified against its manifest checksum before loading.
+func RestoreFiles(dir string, backupPaths ...string) error {
+	type backup struct {
+		path     string
+		manifest *BackupManifest
+	}
+
+	backups := make([]backup, 0, len(backupPaths))
+	for _, backupPath := range backupPaths {
+		manifest, err := ReadManifest(backupPath)
+		if err != nil {
+			return err
+		}
+		backups = append(backups, backup{path: backupPath, manifest: manifest})
+	}
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].manifest.Since < backups[j].manifest.Since
+	})
+
+	for i, b := range backups {
+		if i == 0 && b.manifest.Since != 0 {
+			return fmt.Errorf("%v is an incremental backup since version %v, restore requires a full backup first",
+				b.path, b.manifest.Since)
+		}
+		if i > 0 && b.manifest.Since != backups[i-1].manifest.Version {
+			return fmt.Errorf("%v is a backup since version %v but previous backup %v ends at version %v",
+				b.path, b.manifest.Since, backups[i-1].path, backups[i-1].manifest.Version)
+		}
+		checksum, _, err := fileChecksum(b.path)
+		if err != nil {
+			return err
+		}
+		if checksum != b.manifest.Checksum {
+			return fmt.Errorf("%v checksum %v does not match manifest checksum %v", b.path, checksum, b.manifest.Checksum)
+		}
+	}
+
+	if mkdirErr := os.MkdirAll(dir, os.ModePerm); mkdirErr != nil {
+		return mkdirErr
+	}
+
+	db, err := openDB(dir)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	start := time.Now()
+	for _, b := range backups {
+		if err := loadFile(db.Load, b.path); err != nil {
+			return err
+		}
+		DefaultLogger.Log(LevelInfo, "Restored backup", "keys", b.manifest.Keys, "path", b.path)
+	}
+
+	DefaultLogger.Log(LevelInfo, "Restored backups", "backups", len(backups), "elapsed", time.Since(start))
+	return nil
+}
+
+func loadFile(load func(io.Reader) error, backupPath string) error {
+	f, err := os.Open(backupPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return load(f)
+}
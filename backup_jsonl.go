@@ -0,0 +1,122 @@
+package badgerutils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/dgraph-io/badger/protos"
+)
+
+// BackupRecord is a single key version in a backup file. Keys and values are base64 encoded in JSON.
+type BackupRecord struct {
+	Key       []byte `json:"key"`
+	Value     []byte `json:"value"`
+	Version   uint64 `json:"version"`
+	ExpiresAt uint64 `json:"expires_at,omitempty"`
+	UserMeta  byte   `json:"user_meta,omitempty"`
+}
+
+func kvPairToRecord(kv *protos.KVPair) *BackupRecord {
+	record := &BackupRecord{
+		Key:       kv.Key,
+		Value:     kv.Value,
+		Version:   kv.Version,
+		ExpiresAt: kv.ExpiresAt,
+	}
+	if len(kv.UserMeta) > 0 {
+		record.UserMeta = kv.UserMeta[0]
+	}
+	return record
+}
+
+func recordToKVPair(record *BackupRecord) *protos.KVPair {
+	// Badger's Load expects exactly one byte of user meta on every entry.
+	return &protos.KVPair{
+		Key:       record.Key,
+		Value:     record.Value,
+		UserMeta:  []byte{record.UserMeta},
+		Version:   record.Version,
+		ExpiresAt: record.ExpiresAt,
+	}
+}
+
+// ReadBackup reads the records of a backup from r and calls fn for each record whose key has the given prefix.
+func ReadBackup(r io.Reader, prefix []byte, fn func(*BackupRecord) error) error {
+	return readBackup(r, func(kv *protos.KVPair) error {
+		if !bytes.HasPrefix(kv.Key, prefix) {
+			return nil
+		}
+		return fn(kvPairToRecord(kv))
+	})
+}
+
+// CountBackup returns the number of records in a backup whose key has the given prefix.
+func CountBackup(r io.Reader, prefix []byte) (int, error) {
+	count := 0
+	err := ReadBackup(r, prefix, func(*BackupRecord) error {
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// BackupToJSONLines converts the records of a backup whose key has the given prefix into JSON Lines.
+// It returns the number of records written.
+func BackupToJSONLines(r io.Reader, w io.Writer, prefix []byte) (int, error) {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	count := 0
+	err := ReadBackup(r, prefix, func(record *BackupRecord) error {
+		count++
+		return enc.Encode(record)
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, bw.Flush()
+}
+
+// JSONLinesToBackup converts JSON Lines written by BackupToJSONLines back into a backup that can be restored.
+// It returns the number of records written.
+func JSONLinesToBackup(r io.Reader, w io.Writer) (int, error) {
+	bw := bufio.NewWriter(w)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<30)
+	count, lineNumber := 0, 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		record := &BackupRecord{}
+		if err := json.Unmarshal(line, record); err != nil {
+			return count, fmt.Errorf("line %v: %v", lineNumber, err)
+		}
+		if err := writeKVPair(bw, recordToKVPair(record)); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return count, err
+	}
+	return count, bw.Flush()
+}
+
+// writeKVPair writes a KVPair in the same length-prefixed format as Badger's Backup.
+func writeKVPair(w io.Writer, kv *protos.KVPair) error {
+	if err := binary.Write(w, binary.LittleEndian, uint64(kv.Size())); err != nil {
+		return err
+	}
+	buf, err := kv.Marshal()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
@@ -0,0 +1,64 @@
+package badgerutils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupJSONLines(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := path.Join(tmpDir, "db")
+	err = WriteStream(strings.NewReader("a1:value1\na2:value2\nb1:value3"), dbPath, 2, csvToKeyValue)
+	require.Nil(t, err)
+
+	var backup bytes.Buffer
+	_, err = Backup(dbPath, &backup, 0)
+	require.Nil(t, err)
+
+	count, err := CountBackup(bytes.NewReader(backup.Bytes()), []byte("a"))
+	require.Nil(t, err)
+	require.Equal(t, 2, count)
+
+	var jsonl bytes.Buffer
+	count, err = BackupToJSONLines(bytes.NewReader(backup.Bytes()), &jsonl, nil)
+	require.Nil(t, err)
+	require.Equal(t, 3, count)
+	require.Equal(t, 3, strings.Count(jsonl.String(), "\n"))
+	require.Contains(t, jsonl.String(), `"key":"YTE="`)
+
+	// Edit a value in the JSON Lines and rebuild the backup
+	edited := strings.Replace(jsonl.String(), `"value":"dmFsdWUx"`, `"value":"ZWRpdGVk"`, 1)
+	var rebuilt bytes.Buffer
+	count, err = JSONLinesToBackup(strings.NewReader(edited), &rebuilt)
+	require.Nil(t, err)
+	require.Equal(t, 3, count)
+
+	// Errors report the line number in the input, counting blank lines
+	lines := strings.SplitN(edited, "\n", 2)
+	_, err = JSONLinesToBackup(strings.NewReader(lines[0]+"\n\n\n{broken\n"), ioutil.Discard)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "line 4:")
+
+	restorePath := path.Join(tmpDir, "restored")
+	err = Restore(restorePath, &rebuilt)
+	require.Nil(t, err)
+
+	restoredRecords, err := readDB(restorePath)
+	require.Nil(t, err)
+	require.Equal(t, []sampleRecord{
+		{Key: "a1", Value: "edited"},
+		{Key: "a2", Value: "value2"},
+		{Key: "b1", Value: "value3"},
+	}, restoredRecords)
+}
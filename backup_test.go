@@ -0,0 +1,88 @@
+package badgerutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupRestore(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := path.Join(tmpDir, "db")
+	fullPath := path.Join(tmpDir, "backups", "full.bak")
+	incPath := path.Join(tmpDir, "backups", "inc.bak")
+
+	err = WriteStream(strings.NewReader("key1:value1\nkey2:value2"), dbPath, 2, csvToKeyValue)
+	require.Nil(t, err)
+
+	full, err := BackupFile(dbPath, fullPath, 0)
+	require.Nil(t, err)
+	require.Equal(t, uint64(0), full.Since)
+	require.Equal(t, 2, full.Keys)
+
+	err = WriteStream(strings.NewReader("key2:value2b\nkey3:value3"), dbPath, 2, csvToKeyValue)
+	require.Nil(t, err)
+
+	inc, err := BackupFile(dbPath, incPath, full.Version)
+	require.Nil(t, err)
+	require.Equal(t, full.Version, inc.Since)
+	require.Equal(t, 2, inc.Keys)
+
+	manifest, err := ReadManifest(incPath)
+	require.Nil(t, err)
+	require.Equal(t, inc.Checksum, manifest.Checksum)
+
+	err = RestoreFiles(path.Join(tmpDir, "incremental-only"), incPath)
+	require.NotNil(t, err)
+
+	restorePath := path.Join(tmpDir, "restored")
+	err = RestoreFiles(restorePath, incPath, fullPath)
+	require.Nil(t, err)
+
+	restoredRecords, err := readDB(restorePath)
+	require.Nil(t, err)
+	require.Equal(t, []sampleRecord{
+		{Key: "key1", Value: "value1"},
+		{Key: "key2", Value: "value2b"},
+		{Key: "key3", Value: "value3"},
+	}, restoredRecords)
+}
+
+func TestReadFrames(t *testing.T) {
+	var buf bytes.Buffer
+	for _, frame := range []string{"a", "", "bcd"} {
+		require.Nil(t, binary.Write(&buf, binary.LittleEndian, uint64(len(frame))))
+		buf.WriteString(frame)
+	}
+	frames := make([]string, 0)
+	err := readFrames(&buf, func(frame []byte) error {
+		frames = append(frames, string(frame))
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, []string{"a", "", "bcd"}, frames)
+
+	// A corrupt header is rejected before its frame is allocated
+	buf.Reset()
+	require.Nil(t, binary.Write(&buf, binary.LittleEndian, uint64(1)<<62))
+	err = readFrames(&buf, func([]byte) error { return nil })
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "larger than")
+
+	buf.Reset()
+	require.Nil(t, binary.Write(&buf, binary.LittleEndian, uint64(10)))
+	buf.WriteString("short")
+	err = readFrames(&buf, func([]byte) error { return nil })
+	require.NotNil(t, err)
+}
@@ -0,0 +1,210 @@
+// Package badgerutilstest provides fixtures and assertions for testing code that reads and writes Badger.
+package badgerutilstest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Surfline/badgerutils"
+	"github.com/dgraph-io/badger"
+)
+
+// UpdateGolden makes AssertGolden write the golden file instead of comparing against it. Tests usually set it
+// from a flag, e.g. UpdateGolden = *update.
+var UpdateGolden = false
+
+// TempDB opens a Badger in a new temporary directory with badgerutils.DefaultOptions, modified by each of opts.
+// The DB is closed and the directory removed when the test completes.
+func TempDB(t testing.TB, opts ...func(*badger.Options)) *badger.DB {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "badgerutilstest")
+	if err != nil {
+		t.Fatalf("creating temp dir: %v", err)
+	}
+
+	options := badgerutils.DefaultOptions
+	options.Dir = dir
+	options.ValueDir = dir
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	db, err := badger.Open(options)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("opening temp DB: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.RemoveAll(dir)
+	})
+	return db
+}
+
+// Seed writes kvs into db, committing whenever a transaction becomes too big.
+func Seed(t testing.TB, db *badger.DB, kvs []badgerutils.KeyValue) {
+	t.Helper()
+
+	txn := db.NewTransaction(true)
+	defer func() { txn.Discard() }()
+	for _, kv := range kvs {
+		err := txn.Set(kv.Key, kv.Value)
+		if err == badger.ErrTxnTooBig {
+			if err = txn.Commit(nil); err != nil {
+				t.Fatalf("seeding DB: %v", err)
+			}
+			txn = db.NewTransaction(true)
+			err = txn.Set(kv.Key, kv.Value)
+		}
+		if err != nil {
+			t.Fatalf("seeding key %q: %v", kv.Key, err)
+		}
+	}
+	if err := txn.Commit(nil); err != nil {
+		t.Fatalf("seeding DB: %v", err)
+	}
+}
+
+// Contents returns every key/value of db in key order.
+func Contents(t testing.TB, db *badger.DB) []badgerutils.KeyValue {
+	t.Helper()
+
+	kvs := make([]badgerutils.KeyValue, 0)
+	_, err := badgerutils.Scan(db, badgerutils.ScanOptions{}, func(kv *badgerutils.KeyValue) error {
+		kvs = append(kvs, *kv)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("reading DB: %v", err)
+	}
+	return kvs
+}
+
+// AssertContents fails the test unless db contains exactly the key/values of expected, in any order.
+func AssertContents(t testing.TB, db *badger.DB, expected []badgerutils.KeyValue) {
+	t.Helper()
+
+	want := make([]badgerutils.KeyValue, len(expected))
+	copy(want, expected)
+	sort.Slice(want, func(i, j int) bool {
+		return bytes.Compare(want[i].Key, want[j].Key) < 0
+	})
+	if diff := diffContents(want, Contents(t, db)); diff != "" {
+		t.Errorf("DB contents differ from expected:\n%v", diff)
+	}
+}
+
+// AssertPrefixCount fails the test unless db has n keys with prefix.
+func AssertPrefixCount(t testing.TB, db *badger.DB, prefix []byte, n int) {
+	t.Helper()
+
+	count, err := badgerutils.Count(db, prefix)
+	if err != nil {
+		t.Fatalf("counting prefix %q: %v", prefix, err)
+	}
+	if count != n {
+		t.Errorf("prefix %q has %v keys, expected %v", prefix, count, n)
+	}
+}
+
+// AssertGolden fails the test unless the contents of db match the golden file at path. The golden file has one
+// line per key/value with the quoted key and value separated by a tab. When UpdateGolden is set, the golden file
+// is written from db instead.
+func AssertGolden(t testing.TB, db *badger.DB, path string) {
+	t.Helper()
+
+	got := Contents(t, db)
+	if UpdateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatalf("writing golden file: %v", err)
+		}
+		if err := ioutil.WriteFile(path, formatGolden(got), 0644); err != nil {
+			t.Fatalf("writing golden file: %v", err)
+		}
+		return
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %v", err)
+	}
+	want, err := parseGolden(data)
+	if err != nil {
+		t.Fatalf("parsing golden file %v: %v", path, err)
+	}
+	if diff := diffContents(want, got); diff != "" {
+		t.Errorf("DB contents differ from golden file %v:\n%v", path, diff)
+	}
+}
+
+func formatGolden(kvs []badgerutils.KeyValue) []byte {
+	var buf bytes.Buffer
+	for _, kv := range kvs {
+		fmt.Fprintf(&buf, "%v\t%v\n", strconv.Quote(string(kv.Key)), strconv.Quote(string(kv.Value)))
+	}
+	return buf.Bytes()
+}
+
+func parseGolden(data []byte) ([]badgerutils.KeyValue, error) {
+	kvs := make([]badgerutils.KeyValue, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %v: expected a key and a value separated by a tab", line)
+		}
+		key, err := strconv.Unquote(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %v: key: %v", line, err)
+		}
+		value, err := strconv.Unquote(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %v: value: %v", line, err)
+		}
+		kvs = append(kvs, badgerutils.KeyValue{Key: []byte(key), Value: []byte(value)})
+	}
+	return kvs, scanner.Err()
+}
+
+// diffContents describes the differences between two key ordered lists of key/values, or returns "" when they
+// are equal.
+func diffContents(want, got []badgerutils.KeyValue) string {
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(want) || j < len(got) {
+		var cmp int
+		switch {
+		case i == len(want):
+			cmp = 1
+		case j == len(got):
+			cmp = -1
+		default:
+			cmp = bytes.Compare(want[i].Key, got[j].Key)
+		}
+
+		switch {
+		case cmp < 0:
+			fmt.Fprintf(&diff, "- %q: %q\n", want[i].Key, want[i].Value)
+			i++
+		case cmp > 0:
+			fmt.Fprintf(&diff, "+ %q: %q\n", got[j].Key, got[j].Value)
+			j++
+		default:
+			if !bytes.Equal(want[i].Value, got[j].Value) {
+				fmt.Fprintf(&diff, "~ %q: %q, expected %q\n", want[i].Key, got[j].Value, want[i].Value)
+			}
+			i++
+			j++
+		}
+	}
+	return diff.String()
+}
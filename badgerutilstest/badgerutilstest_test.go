@@ -0,0 +1,71 @@
+package badgerutilstest
+
+import (
+	"flag"
+	"path/filepath"
+	"testing"
+
+	"github.com/Surfline/badgerutils"
+	"github.com/dgraph-io/badger"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "Update golden files")
+
+func sampleKeyValues() []badgerutils.KeyValue {
+	return []badgerutils.KeyValue{
+		{Key: []byte("b/2"), Value: []byte("value\tb2")},
+		{Key: []byte("a/1"), Value: []byte("value a1")},
+		{Key: []byte("b/1"), Value: []byte{0x00, 0xff}},
+	}
+}
+
+func TestTempDB(t *testing.T) {
+	syncWrites := false
+	db := TempDB(t, func(opts *badger.Options) {
+		opts.SyncWrites = true
+		syncWrites = true
+	})
+	require.True(t, syncWrites)
+	require.NotNil(t, db)
+
+	Seed(t, db, sampleKeyValues())
+	AssertContents(t, db, sampleKeyValues())
+	AssertPrefixCount(t, db, []byte("b/"), 2)
+	AssertPrefixCount(t, db, []byte("c/"), 0)
+
+	kvs := Contents(t, db)
+	require.Equal(t, 3, len(kvs))
+	require.Equal(t, "a/1", string(kvs[0].Key))
+}
+
+func TestAssertGolden(t *testing.T) {
+	UpdateGolden = *update
+	defer func() { UpdateGolden = false }()
+
+	db := TempDB(t)
+	Seed(t, db, sampleKeyValues())
+	AssertGolden(t, db, filepath.Join("testdata", "sample.golden"))
+}
+
+func TestDiffContents(t *testing.T) {
+	want := []badgerutils.KeyValue{
+		{Key: []byte("a"), Value: []byte("1")},
+		{Key: []byte("b"), Value: []byte("2")},
+		{Key: []byte("c"), Value: []byte("3")},
+	}
+	got := []badgerutils.KeyValue{
+		{Key: []byte("b"), Value: []byte("20")},
+		{Key: []byte("c"), Value: []byte("3")},
+		{Key: []byte("d"), Value: []byte("4")},
+	}
+	require.Equal(t, `- "a": "1"
+~ "b": "20", expected "2"
++ "d": "4"
+`, diffContents(want, got))
+	require.Equal(t, "", diffContents(want, want))
+
+	kvs, err := parseGolden(formatGolden(sampleKeyValues()))
+	require.Nil(t, err)
+	require.Equal(t, sampleKeyValues(), kvs)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Surfline/badgerutils"
+)
+
+func runBackup(e *env, args []string) error {
+	flags := e.newFlagSet("backup")
+	out := flags.String("out", "", "Path of the backup file to write")
+	from := flags.String("from", "", "Previous backup file to take an incremental backup from")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if err := e.requireDB(); err != nil {
+		return err
+	}
+	if err := requireFlag("out", *out); err != nil {
+		return err
+	}
+
+	var since uint64
+	if *from != "" {
+		manifest, err := badgerutils.ReadManifest(*from)
+		if err != nil {
+			return err
+		}
+		since = manifest.Version
+	}
+
+	manifest, err := badgerutils.BackupFile(e.dir, *out, since)
+	if err != nil {
+		return err
+	}
+
+	return e.output(manifest, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "Version: %v\nKeys: %v\nChecksum: %v\n", manifest.Version, manifest.Keys, manifest.Checksum)
+		return err
+	})
+}
+
+func runRestore(e *env, args []string) error {
+	flags := e.newFlagSet("restore")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if err := e.requireDir(); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return usageError("at least one backup file is required")
+	}
+
+	return badgerutils.RestoreFiles(e.dir, flags.Args()...)
+}
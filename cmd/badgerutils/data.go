@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Surfline/badgerutils"
+)
+
+func outputCount(e *env, count int) error {
+	return e.output(map[string]int{"count": count}, func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, count)
+		return err
+	})
+}
+
+func outputKeyValue(e *env, kv *badgerutils.KeyValue) error {
+	return e.output(kv, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "%s\t%s\n", kv.Key, kv.Value)
+		return err
+	})
+}
+
+func runGet(e *env, args []string) error {
+	flags := e.newFlagSet("get")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := requireArgs(flags, 1, "<key>"); err != nil {
+		return err
+	}
+
+	db, err := e.openExisting()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	key := []byte(flags.Arg(0))
+	value, err := badgerutils.Get(db, key)
+	if err != nil {
+		return err
+	}
+
+	return e.output(&badgerutils.KeyValue{Key: key, Value: value}, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "%s\n", value)
+		return err
+	})
+}
+
+func runPut(e *env, args []string) error {
+	flags := e.newFlagSet("put")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := requireArgs(flags, 2, "<key> <value>"); err != nil {
+		return err
+	}
+
+	db, err := e.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return badgerutils.Put(db, []byte(flags.Arg(0)), []byte(flags.Arg(1)))
+}
+
+func runDelete(e *env, args []string) error {
+	flags := e.newFlagSet("delete")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := requireArgs(flags, 1, "<key>"); err != nil {
+		return err
+	}
+
+	db, err := e.openExisting()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return badgerutils.Delete(db, []byte(flags.Arg(0)))
+}
+
+func runScan(e *env, args []string) error {
+	flags := e.newFlagSet("scan")
+	prefix := flags.String("prefix", "", "Only print keys with this prefix")
+	start := flags.String("start", "", "First key to print")
+	limit := flags.Int("limit", 0, "Maximum number of key/values to print, 0 for no limit")
+	keysOnly := flags.Bool("keys-only", false, "Only print keys")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	db, err := e.openExisting()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	opts := badgerutils.ScanOptions{
+		Prefix:   []byte(*prefix),
+		Start:    []byte(*start),
+		Limit:    *limit,
+		KeysOnly: *keysOnly,
+	}
+	next, err := badgerutils.Scan(db, opts, func(kv *badgerutils.KeyValue) error {
+		return outputKeyValue(e, kv)
+	})
+	if err != nil {
+		return err
+	}
+	if next == nil {
+		return nil
+	}
+	// The key to continue from is the last output, so that it is part of JSON output too
+	return e.output(struct {
+		Next []byte `json:"next"`
+	}{next}, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "Next key: %s\n", next)
+		return err
+	})
+}
+
+func runCount(e *env, args []string) error {
+	flags := e.newFlagSet("count")
+	prefix := flags.String("prefix", "", "Only count keys with this prefix")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	db, err := e.openExisting()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	count, err := badgerutils.Count(db, []byte(*prefix))
+	if err != nil {
+		return err
+	}
+	return outputCount(e, count)
+}
+
+func runGC(e *env, args []string) error {
+	flags := e.newFlagSet("gc")
+	discardRatio := flags.Float64("discard-ratio", 0.5, "Rewrite value log files with at least this ratio of discardable data")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	db, err := e.openExisting()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rewritten, err := badgerutils.GC(db, *discardRatio)
+	if err != nil {
+		return err
+	}
+	return e.output(map[string]int{"rewritten": rewritten}, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "Rewritten value log files: %v\n", rewritten)
+		return err
+	})
+}
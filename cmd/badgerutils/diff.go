@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Surfline/badgerutils"
+)
+
+func runDiff(e *env, args []string) error {
+	flags := e.newFlagSet("diff")
+	other := flags.String("other", "", "Directory of the DB to compare against")
+	prefix := flags.String("prefix", "", "Only compare keys with this prefix")
+	details := flags.Bool("details", false, "Print every difference before the summary")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if err := e.requireDB(); err != nil {
+		return err
+	}
+	if err := requireFlag("other", *other); err != nil {
+		return err
+	}
+
+	var fn func(*badgerutils.Difference) error
+	if *details {
+		fn = func(d *badgerutils.Difference) error {
+			return e.output(d, func(w io.Writer) error {
+				_, err := fmt.Fprintf(w, "%v\t%q\n", d.Kind, d.Key)
+				return err
+			})
+		}
+	}
+
+	summary, err := badgerutils.Diff(e.dir, *other, []byte(*prefix), fn)
+	if err != nil {
+		return err
+	}
+
+	err = e.output(summary, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "Only in A: %v\nOnly in B: %v\nChanged: %v\nEqual: %v\n",
+			summary.OnlyInA, summary.OnlyInB, summary.Changed, summary.Equal)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if !summary.Identical() {
+		return &cliError{code: exitMismatch, err: errors.New("databases differ")}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/Surfline/badgerutils"
+)
+
+// openInput opens the file named by the first argument, or stdin when there are no arguments.
+func openInput(e *env, args []string) (io.ReadCloser, error) {
+	if len(args) == 0 || args[0] == "-" {
+		return ioutil.NopCloser(e.stdin), nil
+	}
+	return os.Open(args[0])
+}
+
+func runBackupList(e *env, args []string) error {
+	flags := e.newFlagSet("backup-list")
+	prefix := flags.String("prefix", "", "Only list keys with this prefix")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	in, err := openInput(e, flags.Args())
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return badgerutils.ReadBackup(in, []byte(*prefix), func(record *badgerutils.BackupRecord) error {
+		return e.output(record, func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, "%q\tversion=%v\texpires_at=%v\tuser_meta=%v\tvalue_size=%v\n",
+				record.Key, record.Version, record.ExpiresAt, record.UserMeta, len(record.Value))
+			return err
+		})
+	})
+}
+
+func runBackupCount(e *env, args []string) error {
+	flags := e.newFlagSet("backup-count")
+	prefix := flags.String("prefix", "", "Only count keys with this prefix")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	in, err := openInput(e, flags.Args())
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	count, err := badgerutils.CountBackup(in, []byte(*prefix))
+	if err != nil {
+		return err
+	}
+	return outputCount(e, count)
+}
+
+func runBackupToJSONLines(e *env, args []string) error {
+	flags := e.newFlagSet("backup-to-jsonl")
+	prefix := flags.String("prefix", "", "Only convert keys with this prefix")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	in, err := openInput(e, flags.Args())
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	_, err = badgerutils.BackupToJSONLines(in, e.stdout, []byte(*prefix))
+	return err
+}
+
+func runJSONLinesToBackup(e *env, args []string) error {
+	flags := e.newFlagSet("jsonl-to-backup")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	in, err := openInput(e, flags.Args())
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	_, err = badgerutils.JSONLinesToBackup(in, e.stdout)
+	return err
+}
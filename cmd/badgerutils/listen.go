@@ -0,0 +1,58 @@
+package main
+
+import (
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Surfline/badgerutils"
+)
+
+func runListen(e *env, args []string) error {
+	flags := e.newFlagSet("listen")
+	network := flags.String("network", "tcp", "Network to listen on: tcp or unix")
+	addr := flags.String("addr", ":7070", "Address or socket path to listen on")
+	batchSize := flags.Int("batch-size", 1000, "Maximum number of records to write per transaction")
+	maxPending := flags.Int("max-pending", 16, "Maximum number of transactions committing at once")
+	delimiter := flags.String("delimiter", ":", "Delimiter between key and value in each line")
+	metricsAddr := flags.String("metrics-addr", "", "Address to serve Prometheus metrics on at /metrics")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *network != "tcp" && *network != "unix" {
+		return usageError("unknown network %v", *network)
+	}
+
+	db, err := e.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	l, err := net.Listen(*network, *addr)
+	if err != nil {
+		return err
+	}
+
+	server := badgerutils.NewIngestServer(db, *batchSize, *maxPending, delimitedToKeyValue(*delimiter))
+
+	if *metricsAddr != "" {
+		serveMetrics(*metricsAddr)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		log.Printf("Shutting down")
+		server.Close()
+	}()
+
+	log.Printf("Listening on %v %v", *network, l.Addr())
+	if err := server.Serve(l); err != nil {
+		return err
+	}
+	return server.Close()
+}
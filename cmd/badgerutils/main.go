@@ -0,0 +1,264 @@
+// Command badgerutils provides subcommands for reading, writing and operating on Badger directories.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/Surfline/badgerutils"
+	"github.com/dgraph-io/badger"
+	"github.com/dgraph-io/badger/options"
+)
+
+// Exit codes
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitUsage    = 2
+	exitNotFound = 3
+	exitMismatch = 4
+)
+
+// cliError carries the exit code of a failed command.
+type cliError struct {
+	code int
+	err  error
+}
+
+func (e *cliError) Error() string {
+	return e.err.Error()
+}
+
+func usageError(format string, a ...interface{}) error {
+	return &cliError{code: exitUsage, err: fmt.Errorf(format, a...)}
+}
+
+func exitCode(err error) int {
+	if cerr, ok := err.(*cliError); ok {
+		return cerr.code
+	}
+	if err == badger.ErrKeyNotFound {
+		return exitNotFound
+	}
+	return exitError
+}
+
+// env holds the global flags shared by every command.
+type env struct {
+	dir    string
+	format string
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (e *env) requireDir() error {
+	if e.dir == "" {
+		return usageError("dir flag is required")
+	}
+	return nil
+}
+
+// requireDB checks that the dir flag names an existing directory, so that commands reading a DB fail on a mistyped
+// path instead of creating an empty DB there.
+func (e *env) requireDB() error {
+	if err := e.requireDir(); err != nil {
+		return err
+	}
+	if info, err := os.Stat(e.dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("no DB directory at %v", e.dir)
+	}
+	return nil
+}
+
+// open opens the DB of the dir flag, creating its directory for commands that write.
+func (e *env) open() (*badger.DB, error) {
+	if err := e.requireDir(); err != nil {
+		return nil, err
+	}
+	return badgerutils.Open(e.dir)
+}
+
+// openExisting opens the DB of the dir flag for commands that only read it or change existing keys.
+func (e *env) openExisting() (*badger.DB, error) {
+	if err := e.requireDB(); err != nil {
+		return nil, err
+	}
+	return badgerutils.Open(e.dir)
+}
+
+func (e *env) json() bool {
+	return e.format == "json"
+}
+
+// output writes v as JSON when the json format is selected, otherwise it calls text.
+func (e *env) output(v interface{}, text func(w io.Writer) error) error {
+	if e.json() {
+		return json.NewEncoder(e.stdout).Encode(v)
+	}
+	return text(e.stdout)
+}
+
+type command struct {
+	usage string
+	run   func(e *env, args []string) error
+}
+
+var commands = map[string]command{
+	"write":           {"Write key/values read from stdin", runWrite},
+	"get":             {"Print the value of a key", runGet},
+	"put":             {"Set the value of a key", runPut},
+	"delete":          {"Delete a key", runDelete},
+	"scan":            {"Print key/values in key order", runScan},
+	"count":           {"Count keys", runCount},
+	"export":          {"Export key/values to stdout or one file per partition", runExport},
+	"stats":           {"Report the sizes and key space of a DB", runStats},
+	"backup":          {"Write a full or incremental backup of a DB", runBackup},
+	"restore":         {"Restore a full backup and its incrementals into a DB", runRestore},
+	"gc":              {"Run value log garbage collection", runGC},
+	"shell":           {"Browse a DB interactively, read-only unless started with -write", runShell},
+	"serve":           {"Serve an HTTP/JSON API over a DB", runServe},
+	"listen":          {"Ingest newline-delimited records from TCP or Unix socket clients", runListen},
+	"redis":           {"Serve a subset of the Redis protocol over a DB", runRedis},
+	"reshard":         {"Copy a DB or its shards into a new set of hash or range shards", runReshard},
+	"publish":         {"Write stdin into a new version of a dataset and publish it as current", runPublish},
+	"rollback":        {"Point current at the previous or a given version of a dataset", runRollback},
+	"diff":            {"Compare the keys and values of two DBs", runDiff},
+	"verify":          {"Compare the digest of a DB against the input file it was written from", runVerify},
+	"backup-list":     {"List the records of a backup file", runBackupList},
+	"backup-count":    {"Count the records of a backup file", runBackupCount},
+	"backup-to-jsonl": {"Convert a backup file to JSON Lines", runBackupToJSONLines},
+	"jsonl-to-backup": {"Convert JSON Lines to a backup file", runJSONLinesToBackup},
+}
+
+func usage(flags *flag.FlagSet) {
+	w := flags.Output()
+	fmt.Fprintf(w, "Usage: badgerutils [global flags] <command> [flags] [args]\n\nGlobal flags:\n")
+	flags.PrintDefaults()
+	fmt.Fprintf(w, "\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-16v %v\n", name, commands[name].usage)
+	}
+}
+
+var loadingModes = map[string]options.FileLoadingMode{
+	"fileio": options.FileIO,
+	"mmap":   options.MemoryMap,
+	"memory": options.LoadToRAM,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command line args with the given standard streams and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	e := &env{stdin: stdin, stdout: stdout, stderr: stderr}
+	global := flag.NewFlagSet("badgerutils", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	global.StringVar(&e.dir, "dir", "", "Directory of the DB")
+	global.StringVar(&e.format, "format", "text", "Output format: text or json")
+	readOnly := global.Bool("read-only", false, "Open the DB read-only")
+	syncWrites := global.Bool("sync-writes", false, "Sync all writes to disk")
+	truncate := global.Bool("truncate", false, "Truncate corrupt data at the end of the value log")
+	loadingMode := global.String("loading-mode", "fileio", "How tables and value logs are loaded: fileio, mmap or memory")
+	logLevel := global.String("log-level", "info", "Minimum level of logs written to stderr: debug, info, warn, error or none")
+	global.Usage = func() { usage(global) }
+	if err := global.Parse(args); err == flag.ErrHelp {
+		return exitOK
+	} else if err != nil {
+		return exitUsage
+	}
+
+	if global.NArg() == 0 {
+		usage(global)
+		return exitUsage
+	}
+
+	name := global.Arg(0)
+	cmd, ok := commands[name]
+	mode, modeOK := loadingModes[*loadingMode]
+	level, levelErr := badgerutils.ParseLevel(*logLevel)
+	var err error
+	switch {
+	case !ok:
+		err = usageError("unknown command %v", name)
+	case e.format != "text" && e.format != "json":
+		err = usageError("unknown format %v", e.format)
+	case !modeOK:
+		err = usageError("unknown loading mode %v", *loadingMode)
+	case levelErr != nil && *logLevel != "none":
+		err = usageError("unknown log level %v", *logLevel)
+	default:
+		badgerutils.DefaultLogger = badgerutils.NopLogger{}
+		if *logLevel != "none" {
+			badgerutils.DefaultLogger = badgerutils.NewStdLogger(log.New(stderr, "", log.LstdFlags), level)
+		}
+		badgerutils.RouteStdLog(badgerutils.DefaultLogger, badgerutils.LevelInfo)
+		badgerutils.DefaultOptions.ReadOnly = *readOnly
+		badgerutils.DefaultOptions.SyncWrites = *syncWrites
+		badgerutils.DefaultOptions.Truncate = *truncate
+		badgerutils.DefaultOptions.TableLoadingMode = mode
+		badgerutils.DefaultOptions.ValueLogLoadingMode = mode
+		err = cmd.run(e, global.Args()[1:])
+	}
+
+	if err != nil {
+		return reportError(e, name, err)
+	}
+	return exitOK
+}
+
+// reportError writes a summary of err to stderr and returns the exit code.
+func reportError(e *env, name string, err error) int {
+	code := exitCode(err)
+	if e.json() {
+		json.NewEncoder(e.stderr).Encode(struct {
+			Command string `json:"command"`
+			Error   string `json:"error"`
+			Code    int    `json:"code"`
+		}{name, err.Error(), code})
+	} else {
+		fmt.Fprintf(e.stderr, "badgerutils %v: %v\n", name, err)
+	}
+	return code
+}
+
+// newFlagSet returns a flag set for a command that reports parsing errors instead of exiting and writes its usage
+// to the stderr of e.
+func (e *env) newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(e.stderr)
+	return flags
+}
+
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		return &cliError{code: exitUsage, err: err}
+	}
+	return nil
+}
+
+func requireFlag(name, value string) error {
+	if value == "" {
+		return usageError("%v flag is required", name)
+	}
+	return nil
+}
+
+func requireArgs(flags *flag.FlagSet, n int, names string) error {
+	if flags.NArg() != n {
+		return usageError("expected arguments: %v", names)
+	}
+	return nil
+}
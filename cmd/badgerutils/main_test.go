@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := path.Join(tmpDir, "db")
+	typoPath := path.Join(tmpDir, "typo")
+
+	tests := []struct {
+		name   string
+		args   []string
+		stdin  string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{name: "no command", args: []string{}, code: exitUsage, stderr: "Usage: badgerutils"},
+		{name: "unknown command", args: []string{"-dir=" + dbPath, "nope"}, code: exitUsage, stderr: "unknown command nope"},
+		{name: "unknown format", args: []string{"-format=xml", "count"}, code: exitUsage, stderr: "unknown format xml"},
+		{name: "unknown global flag", args: []string{"-nope", "count"}, code: exitUsage, stderr: "flag provided but not defined"},
+		{name: "missing dir", args: []string{"get", "key1"}, code: exitUsage, stderr: "dir flag is required"},
+		{name: "write", args: []string{"-dir=" + dbPath, "write"}, stdin: "key1:value1\nkey2:value2\nother:value3\n", code: exitOK},
+		{name: "get", args: []string{"-dir=" + dbPath, "get", "key1"}, code: exitOK, stdout: "value1\n"},
+		{name: "get json", args: []string{"-dir=" + dbPath, "-format=json", "get", "key2"}, code: exitOK,
+			stdout: `{"key":"a2V5Mg==","value":"dmFsdWUy"}` + "\n"},
+		{name: "get missing key", args: []string{"-dir=" + dbPath, "get", "key3"}, code: exitNotFound, stderr: "Key not found"},
+		{name: "get without key", args: []string{"-dir=" + dbPath, "get"}, code: exitUsage, stderr: "expected arguments: <key>"},
+		{name: "unknown command flag", args: []string{"-dir=" + dbPath, "count", "-nope"}, code: exitUsage,
+			stderr: "flag provided but not defined: -nope\nUsage of count:"},
+		{name: "count", args: []string{"-dir=" + dbPath, "count", "-prefix=key"}, code: exitOK, stdout: "2\n"},
+		{name: "count json", args: []string{"-dir=" + dbPath, "-format=json", "count"}, code: exitOK, stdout: `{"count":3}` + "\n"},
+		{name: "scan", args: []string{"-dir=" + dbPath, "scan", "-start=key2"}, code: exitOK, stdout: "key2\tvalue2\nother\tvalue3\n"},
+		{name: "scan page", args: []string{"-dir=" + dbPath, "scan", "-limit=1"}, code: exitOK,
+			stdout: "key1\tvalue1\nNext key: key2\n"},
+		{name: "scan page json", args: []string{"-dir=" + dbPath, "-format=json", "scan", "-limit=1", "-keys-only"}, code: exitOK,
+			stdout: `{"key":"a2V5MQ==","value":null}` + "\n" + `{"next":"a2V5Mg=="}` + "\n"},
+		{name: "read-only get", args: []string{"-dir=" + dbPath, "-read-only", "get", "other"}, code: exitOK, stdout: "value3\n"},
+		{name: "get from mistyped dir", args: []string{"-dir=" + typoPath, "get", "key1"}, code: exitError,
+			stderr: "no DB directory at " + typoPath},
+		{name: "stats of mistyped dir", args: []string{"-dir=" + typoPath, "stats"}, code: exitError, stderr: "no DB directory"},
+		{name: "export of mistyped dir", args: []string{"-dir=" + typoPath, "export"}, code: exitError, stderr: "no DB directory"},
+		{name: "serve of mistyped dir", args: []string{"-dir=" + typoPath, "serve", "-write", "-addr=127.0.0.1:0"}, code: exitError,
+			stderr: "no DB directory"},
+		{name: "json error", args: []string{"-dir=" + typoPath, "-format=json", "count"}, code: exitError,
+			stderr: `{"command":"count","error":"no DB directory at ` + typoPath + `","code":1}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"-log-level=none"}, test.args...)
+			code := run(args, strings.NewReader(test.stdin), &stdout, &stderr)
+			require.Equal(t, test.code, code, "stderr: %v", stderr.String())
+			if test.code == exitOK {
+				require.Equal(t, test.stdout, stdout.String())
+			}
+			require.Contains(t, stderr.String(), test.stderr)
+		})
+	}
+
+	// Read commands never create the directory of a mistyped path
+	_, err = os.Stat(typoPath)
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Surfline/badgerutils"
+)
+
+// delimitedToKeyValue returns a parser that splits each line into a key and value at the first delimiter.
+func delimitedToKeyValue(delimiter string) func(string) (*badgerutils.KeyValue, error) {
+	return func(line string) (*badgerutils.KeyValue, error) {
+		kv := strings.SplitN(line, delimiter, 2)
+		if len(kv) < 2 {
+			return nil, fmt.Errorf("%v has no %q delimiter", line, delimiter)
+		}
+
+		return &badgerutils.KeyValue{
+			Key:   []byte(kv[0]),
+			Value: []byte(kv[1]),
+		}, nil
+	}
+}
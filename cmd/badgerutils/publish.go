@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Surfline/badgerutils"
+)
+
+func runPublish(e *env, args []string) error {
+	flags := e.newFlagSet("publish")
+	version := flags.String("version", "", "Name of the new version, defaults to the current UTC time")
+	batchSize := flags.Int("batch-size", 1000, "Number of records to write per transaction")
+	delimiter := flags.String("delimiter", ":", "Delimiter between key and value in each line")
+	minRecords := flags.Int("min-records", 0, "Fail unless the new version has at least this many keys")
+	keep := flags.Int("keep", 0, "Number of versions to keep, or 0 to keep all")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if err := e.requireDir(); err != nil {
+		return err
+	}
+
+	opts := badgerutils.PublishOptions{Version: *version, MinRecords: *minRecords, Keep: *keep}
+	dir, err := badgerutils.BuildAndPublish(e.dir, opts, func(dir string) error {
+		return badgerutils.WriteStream(e.stdin, dir, *batchSize, delimitedToKeyValue(*delimiter))
+	})
+	if err != nil {
+		return err
+	}
+	return e.output(map[string]string{"published": dir}, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "Published %v\n", dir)
+		return err
+	})
+}
+
+func runRollback(e *env, args []string) error {
+	flags := e.newFlagSet("rollback")
+	to := flags.String("to", "", "Version to publish, defaults to the version before the current one")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if err := e.requireDir(); err != nil {
+		return err
+	}
+
+	version := *to
+	if version == "" {
+		previous, err := badgerutils.Rollback(e.dir)
+		if err != nil {
+			return err
+		}
+		version = previous
+	} else if err := badgerutils.Publish(e.dir, version); err != nil {
+		return err
+	}
+	return e.output(map[string]string{"current": version}, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "Current version %v\n", version)
+		return err
+	})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Surfline/badgerutils"
+)
+
+func runReshard(e *env, args []string) error {
+	flags := e.newFlagSet("reshard")
+	fromShards := flags.Int("from-shards", 0, "Number of shards under -dir, or 0 when -dir is a single DB")
+	toDir := flags.String("to-dir", "", "Directory to write the new shards under")
+	toShards := flags.Int("to-shards", 0, "Number of hash shards to write")
+	splits := flags.String("splits", "", "Comma separated split keys of range shards to write instead of hash shards")
+	batchSize := flags.Int("batch-size", 1000, "Number of records to write per transaction")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if err := e.requireDB(); err != nil {
+		return err
+	}
+	if err := requireFlag("to-dir", *toDir); err != nil {
+		return err
+	}
+
+	var router badgerutils.Router
+	switch {
+	case *splits != "":
+		keys := make([][]byte, 0)
+		for _, split := range strings.Split(*splits, ",") {
+			keys = append(keys, []byte(split))
+		}
+		router = badgerutils.NewRangeRouter(keys)
+	case *toShards > 0:
+		router = badgerutils.NewHashRouter(*toShards)
+	default:
+		return usageError("-to-shards or -splits is required")
+	}
+
+	srcDirs := []string{e.dir}
+	if *fromShards > 0 {
+		srcDirs = badgerutils.ShardDirs(e.dir, *fromShards)
+	}
+	dstDirs := badgerutils.ShardDirs(*toDir, router.Shards())
+	if err := badgerutils.Reshard(srcDirs, dstDirs, router, *batchSize); err != nil {
+		return err
+	}
+
+	return e.output(map[string][]string{"shards": dstDirs}, func(w io.Writer) error {
+		for _, dir := range dstDirs {
+			if _, err := fmt.Fprintln(w, dir); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Surfline/badgerutils"
+)
+
+func runServe(e *env, args []string) error {
+	flags := e.newFlagSet("serve")
+	addr := flags.String("addr", ":8080", "Address to listen on")
+	write := flags.Bool("write", false, "Enable the POST /batch write endpoint")
+	metrics := flags.Bool("metrics", false, "Serve Prometheus metrics at /metrics")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	badgerutils.DefaultOptions.ReadOnly = !*write
+	db, err := e.openExisting()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	opts := badgerutils.HandlerOptions{AllowWrites: *write}
+	if *metrics {
+		m := badgerutils.NewPrometheusMetrics()
+		badgerutils.DefaultMetrics = m
+		opts.Metrics = m
+	}
+	handler := badgerutils.NewHandler(db, opts)
+	log.Printf("Listening on %v", *addr)
+	return http.ListenAndServe(*addr, handler)
+}
+
+// serveMetrics collects metrics and serves them in the background at addr.
+func serveMetrics(addr string) {
+	m := badgerutils.NewPrometheusMetrics()
+	badgerutils.DefaultMetrics = m
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	go func() {
+		log.Printf("Serving metrics on %v", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("Metrics server: %v", err)
+		}
+	}()
+}
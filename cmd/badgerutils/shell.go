@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Surfline/badgerutils"
+	"github.com/dgraph-io/badger"
+)
+
+const shellPageSize = 10
+
+const shellHelp = `Commands:
+  get <key>               Print the value of a key
+  scan [prefix] [limit]   Print key/values with a prefix
+  seek <key>              Print key/values starting at a key
+  next                    Print the next page after scan or seek
+  count [prefix]          Count keys with a prefix
+  set <key> <value>       Set the value of a key (requires -write)
+  del <key>               Delete a key (requires -write)
+  keys hex|text           Display keys as hex or text
+  history                 Print command history
+  help                    Print this help
+  exit                    Exit the shell
+
+Keys starting with 0x are parsed as hex.
+`
+
+// shell is an interactive session over an open DB.
+type shell struct {
+	db       *badger.DB
+	write    bool
+	hexKeys  bool
+	cursor   keyCursor
+	history  []string
+	histFile io.Writer
+	out      io.Writer
+}
+
+// keyCursor is the position to continue from with next.
+type keyCursor struct {
+	prefix []byte
+	next   []byte
+}
+
+func runShell(e *env, args []string) error {
+	flags := e.newFlagSet("shell")
+	write := flags.Bool("write", false, "Allow set and del commands")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		e.dir = flags.Arg(0)
+	}
+
+	badgerutils.DefaultOptions.ReadOnly = !*write
+	open := e.openExisting
+	if *write {
+		open = e.open
+	}
+	db, err := open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	sh := &shell{db: db, write: *write, out: e.stdout}
+	if home, err := os.UserHomeDir(); err == nil {
+		histPath := filepath.Join(home, ".badgerutils_history")
+		sh.loadHistory(histPath)
+		if f, err := os.OpenFile(histPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600); err == nil {
+			defer f.Close()
+			sh.histFile = f
+		}
+	}
+	return sh.run(e.stdin)
+}
+
+func (sh *shell) loadHistory(histPath string) {
+	f, err := os.Open(histPath)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		sh.history = append(sh.history, scanner.Text())
+	}
+}
+
+func (sh *shell) run(in io.Reader) error {
+	mode := "read-only"
+	if sh.write {
+		mode = "read-write"
+	}
+	fmt.Fprintf(sh.out, "Opened %v. Type help for commands.\n", mode)
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(sh.out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(sh.out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		sh.history = append(sh.history, line)
+		if sh.histFile != nil {
+			fmt.Fprintln(sh.histFile, line)
+		}
+
+		fields := strings.Fields(line)
+		if fields[0] == "exit" || fields[0] == "quit" {
+			return nil
+		}
+		if err := sh.exec(fields[0], fields[1:]); err != nil {
+			fmt.Fprintf(sh.out, "error: %v\n", err)
+		}
+	}
+}
+
+func (sh *shell) exec(name string, args []string) error {
+	switch name {
+	case "help":
+		fmt.Fprint(sh.out, shellHelp)
+	case "history":
+		for i, line := range sh.history {
+			fmt.Fprintf(sh.out, "%5d  %v\n", i+1, line)
+		}
+	case "keys":
+		if len(args) != 1 || (args[0] != "hex" && args[0] != "text") {
+			return fmt.Errorf("usage: keys hex|text")
+		}
+		sh.hexKeys = args[0] == "hex"
+	case "get":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: get <key>")
+		}
+		key, err := parseKey(args[0])
+		if err != nil {
+			return err
+		}
+		value, err := badgerutils.Get(sh.db, key)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(sh.out, formatValue(value))
+	case "scan":
+		if len(args) > 2 {
+			return fmt.Errorf("usage: scan [prefix] [limit]")
+		}
+		var prefix []byte
+		var err error
+		if len(args) > 0 {
+			if prefix, err = parseKey(args[0]); err != nil {
+				return err
+			}
+		}
+		limit := shellPageSize
+		if len(args) == 2 {
+			if limit, err = strconv.Atoi(args[1]); err != nil {
+				return err
+			}
+		}
+		return sh.page(keyCursor{prefix: prefix, next: prefix}, limit)
+	case "seek":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: seek <key>")
+		}
+		key, err := parseKey(args[0])
+		if err != nil {
+			return err
+		}
+		return sh.page(keyCursor{next: key}, shellPageSize)
+	case "next":
+		if sh.cursor.next == nil {
+			return fmt.Errorf("no more keys, start with scan or seek")
+		}
+		return sh.page(sh.cursor, shellPageSize)
+	case "count":
+		if len(args) > 1 {
+			return fmt.Errorf("usage: count [prefix]")
+		}
+		var prefix []byte
+		if len(args) == 1 {
+			var err error
+			if prefix, err = parseKey(args[0]); err != nil {
+				return err
+			}
+		}
+		count, err := badgerutils.Count(sh.db, prefix)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(sh.out, count)
+	case "set":
+		if !sh.write {
+			return fmt.Errorf("set requires the shell to be started with -write")
+		}
+		if len(args) < 2 {
+			return fmt.Errorf("usage: set <key> <value>")
+		}
+		key, err := parseKey(args[0])
+		if err != nil {
+			return err
+		}
+		return badgerutils.Put(sh.db, key, []byte(strings.Join(args[1:], " ")))
+	case "del":
+		if !sh.write {
+			return fmt.Errorf("del requires the shell to be started with -write")
+		}
+		if len(args) != 1 {
+			return fmt.Errorf("usage: del <key>")
+		}
+		key, err := parseKey(args[0])
+		if err != nil {
+			return err
+		}
+		return badgerutils.Delete(sh.db, key)
+	default:
+		return fmt.Errorf("unknown command %v, type help for commands", name)
+	}
+	return nil
+}
+
+// page prints up to limit key/values from the cursor and moves the cursor to the following key.
+func (sh *shell) page(cursor keyCursor, limit int) error {
+	opts := badgerutils.ScanOptions{Prefix: cursor.prefix, Start: cursor.next, Limit: limit}
+	next, err := badgerutils.Scan(sh.db, opts, func(kv *badgerutils.KeyValue) error {
+		_, err := fmt.Fprintf(sh.out, "%v\t%v\n", sh.formatKey(kv.Key), formatValue(kv.Value))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	sh.cursor = keyCursor{prefix: cursor.prefix, next: next}
+	if next != nil {
+		fmt.Fprintln(sh.out, "(more, type next)")
+	}
+	return nil
+}
+
+func (sh *shell) formatKey(key []byte) string {
+	if sh.hexKeys {
+		return "0x" + hex.EncodeToString(key)
+	}
+	return strconv.Quote(string(key))
+}
+
+// formatValue pretty-prints JSON values and quotes other values.
+func formatValue(value []byte) string {
+	if json.Valid(value) {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, value, "", "  "); err == nil {
+			return buf.String()
+		}
+	}
+	return strconv.Quote(string(value))
+}
+
+func parseKey(s string) ([]byte, error) {
+	if strings.HasPrefix(s, "0x") {
+		return hex.DecodeString(s[2:])
+	}
+	return []byte(s), nil
+}
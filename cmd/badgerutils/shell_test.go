@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Surfline/badgerutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShell(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	db, err := badgerutils.Open(path.Join(tmpDir, "db"))
+	require.Nil(t, err)
+	defer db.Close()
+
+	var out, hist bytes.Buffer
+	sh := &shell{db: db, write: true, out: &out, histFile: &hist}
+	script := []string{
+		"set a1 one",
+		"set a2 {\"swell\":3}",
+		"set 0x00ff binary value",
+		"set b1 other",
+		"get a1",
+		"get a2",
+		"get 0x00ff",
+		"get missing",
+		"scan a 1",
+		"next",
+		"next",
+		"keys hex",
+		"seek 0x00",
+		"keys text",
+		"count a",
+		"count",
+		"del a1",
+		"count a",
+		"keys octal",
+		"get",
+		"nope",
+		"exit",
+		"count",
+	}
+	require.Nil(t, sh.run(strings.NewReader(strings.Join(script, "\n")+"\n")))
+
+	expected := `Opened read-write. Type help for commands.
+> > > > > "one"
+> {
+  "swell": 3
+}
+> "binary value"
+> error: Key not found
+> "a1"	"one"
+(more, type next)
+> "a2"	{
+  "swell": 3
+}
+> error: no more keys, start with scan or seek
+> > 0x00ff	"binary value"
+0x6131	"one"
+0x6132	{
+  "swell": 3
+}
+0x6231	"other"
+> > 2
+> 4
+> > 1
+> error: usage: keys hex|text
+> error: usage: get <key>
+> error: unknown command nope, type help for commands
+> `
+	require.Equal(t, expected, out.String())
+	require.Equal(t, strings.Join(script[:len(script)-1], "\n")+"\n", hist.String())
+	require.Equal(t, script[:len(script)-1], sh.history)
+}
+
+func TestShellCommand(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	home := os.Getenv("HOME")
+	defer os.Setenv("HOME", home)
+	require.Nil(t, os.Setenv("HOME", tmpDir))
+	histPath := filepath.Join(tmpDir, ".badgerutils_history")
+	require.Nil(t, ioutil.WriteFile(histPath, []byte("count\n"), 0600))
+
+	dbPath := path.Join(tmpDir, "db")
+	shell := func(stdin string, args ...string) (int, string) {
+		var stdout, stderr bytes.Buffer
+		code := run(append([]string{"-log-level=none", "-dir=" + dbPath, "shell"}, args...), strings.NewReader(stdin),
+			&stdout, &stderr)
+		return code, stdout.String() + stderr.String()
+	}
+
+	// A read-only shell does not create a missing DB
+	code, out := shell("")
+	require.Equal(t, exitError, code)
+	require.Contains(t, out, "no DB directory")
+
+	code, out = shell("set key1 value1\nhistory\n", "-write")
+	require.Equal(t, exitOK, code)
+	require.Contains(t, out, "Opened read-write.")
+	require.Contains(t, out, "    1  count\n    2  set key1 value1\n    3  history\n")
+
+	code, out = shell("get key1\nset key2 value2\n")
+	require.Equal(t, exitOK, code)
+	require.Equal(t, "Opened read-only. Type help for commands.\n> \"value1\"\n"+
+		"> error: set requires the shell to be started with -write\n> \n", out)
+
+	history, err := ioutil.ReadFile(histPath)
+	require.Nil(t, err)
+	require.Equal(t, "count\nset key1 value1\nhistory\nget key1\nset key2 value2\n", string(history))
+}
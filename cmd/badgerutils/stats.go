@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/Surfline/badgerutils"
+)
+
+func runStats(e *env, args []string) error {
+	flags := e.newFlagSet("stats")
+	delimiter := flags.String("prefix-delimiter", "", "Delimiter between key segments, each byte is a segment when empty")
+	depth := flags.Int("prefix-depth", 0, "Number of key segments to group prefixes by")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if err := e.requireDB(); err != nil {
+		return err
+	}
+
+	stats, err := badgerutils.Stats(e.dir, badgerutils.StatsOptions{
+		PrefixDelimiter: *delimiter,
+		PrefixDepth:     *depth,
+	})
+	if err != nil {
+		return err
+	}
+
+	return e.output(stats, stats.WriteText)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Surfline/badgerutils"
+)
+
+func runVerify(e *env, args []string) error {
+	flags := e.newFlagSet("verify")
+	input := flags.String("input", "", "Input file the DB was written from")
+	prefix := flags.String("prefix", "", "Only verify keys with this prefix")
+	delimiter := flags.String("delimiter", ":", "Delimiter between key and value in each input line")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if err := e.requireDB(); err != nil {
+		return err
+	}
+	if err := requireFlag("input", *input); err != nil {
+		return err
+	}
+
+	f, err := os.Open(*input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	inputDigest, err := badgerutils.DigestStream(f, []byte(*prefix), delimitedToKeyValue(*delimiter))
+	if err != nil {
+		return err
+	}
+
+	dbDigest, err := badgerutils.Digest(e.dir, []byte(*prefix))
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		Input *badgerutils.DBDigest `json:"input"`
+		DB    *badgerutils.DBDigest `json:"db"`
+	}{inputDigest, dbDigest}
+	err = e.output(result, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "Input: %v records, checksum %v\nDB: %v records, checksum %v\n",
+			inputDigest.Count, inputDigest.Checksum, dbDigest.Count, dbDigest.Checksum)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if *inputDigest != *dbDigest {
+		return &cliError{code: exitMismatch, err: errors.New("DB digest does not match input digest")}
+	}
+	return nil
+}
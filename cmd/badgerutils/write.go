@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Surfline/badgerutils"
+)
+
+func runWrite(e *env, args []string) error {
+	flags := e.newFlagSet("write")
+	batchSize := flags.Int("batch-size", 1000, "Number of records to write per transaction")
+	delimiter := flags.String("delimiter", ":", "Delimiter between key and value in each line")
+	also := flags.String("also", "", "Comma separated directories of more DBs to write the same records into")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if err := e.requireDir(); err != nil {
+		return err
+	}
+
+	if *also == "" {
+		return badgerutils.WriteStream(e.stdin, e.dir, *batchSize, delimitedToKeyValue(*delimiter))
+	}
+
+	destinations := []badgerutils.Destination{{Dir: e.dir}}
+	for _, dir := range strings.Split(*also, ",") {
+		destinations = append(destinations, badgerutils.Destination{Dir: dir})
+	}
+	_, err := badgerutils.WriteStreamMulti(context.Background(), e.stdin, destinations, *batchSize,
+		delimitedToKeyValue(*delimiter))
+	return err
+}
+
+func runExport(e *env, args []string) error {
+	flags := e.newFlagSet("export")
+	partitions := flags.Int("partitions", 8, "Number of key ranges to read concurrently")
+	outDir := flags.String("out-dir", "", "Write one file per partition to this directory instead of stdout")
+	delimiter := flags.String("delimiter", ":", "Delimiter between key and value in each line")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if err := e.requireDB(); err != nil {
+		return err
+	}
+
+	keyValueToLine := func(kv *badgerutils.KeyValue) (string, error) {
+		return fmt.Sprintf("%s%s%s", kv.Key, *delimiter, kv.Value), nil
+	}
+
+	if *outDir == "" {
+		return badgerutils.ExportStream(e.stdout, e.dir, *partitions, keyValueToLine)
+	}
+
+	files, err := badgerutils.ExportFiles(*outDir, e.dir, *partitions, keyValueToLine)
+	if err != nil {
+		return err
+	}
+	return e.output(map[string][]string{"files": files}, func(w io.Writer) error {
+		for _, file := range files {
+			if _, err := fmt.Fprintln(w, file); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
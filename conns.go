@@ -0,0 +1,110 @@
+package badgerutils
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// connTracker tracks the listeners and connections of a server so they can be closed together.
+type connTracker struct {
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	handlers  sync.WaitGroup
+}
+
+func newConnTracker() *connTracker {
+	return &connTracker{
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// serve accepts connections on l and calls handle for each in its own goroutine until close is called.
+func (t *connTracker) serve(l net.Listener, handle func(net.Conn)) error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return fmt.Errorf("server closed")
+	}
+	t.listeners[l] = struct{}{}
+	t.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if t.isClosed() {
+				return nil
+			}
+			return err
+		}
+
+		t.mu.Lock()
+		if t.closed {
+			t.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		t.conns[conn] = struct{}{}
+		t.handlers.Add(1)
+		t.mu.Unlock()
+
+		go func() {
+			defer func() {
+				t.mu.Lock()
+				delete(t.conns, conn)
+				t.mu.Unlock()
+				conn.Close()
+				t.handlers.Done()
+			}()
+			handle(conn)
+		}()
+	}
+}
+
+// close stops accepting connections, closes open connections and waits for their handlers to return.
+func (t *connTracker) close() {
+	t.mu.Lock()
+	t.closed = true
+	for l := range t.listeners {
+		l.Close()
+	}
+	for conn := range t.conns {
+		conn.Close()
+	}
+	t.mu.Unlock()
+
+	t.handlers.Wait()
+}
+
+func (t *connTracker) isClosed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.closed
+}
+
+// errLineTooLong is returned by readLine for lines longer than its limit.
+var errLineTooLong = errors.New("line too long")
+
+// readLine reads a line of up to max bytes from r without its line ending, so that a client that never ends a line
+// cannot grow the buffered line without bound. The last line may end without a newline, with io.EOF returned with
+// it.
+func readLine(r *bufio.Reader, max int) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > max {
+			return nil, errLineTooLong
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		return bytes.TrimRight(line, "\r\n"), err
+	}
+}
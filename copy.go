@@ -0,0 +1,115 @@
+package badgerutils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dgraph-io/badger"
+)
+
+// CopyOptions defines which records Copy reads and how they are written into the destination.
+type CopyOptions struct {
+	// BatchSize is the number of records to write per transaction. Defaults to 1000.
+	BatchSize int
+	// Prefixes limits the copy to keys with one of these prefixes. All keys are copied when empty.
+	Prefixes [][]byte
+	// FromPrefix is replaced with ToPrefix on every copied key that starts with FromPrefix.
+	FromPrefix []byte
+	ToPrefix   []byte
+	// Transform is applied to every record after prefix rewriting. Returning a nil KeyValue drops the record.
+	Transform func(*KeyValue) (*KeyValue, error)
+	// Conflict defines how keys that already exist in the destination are handled.
+	Conflict ConflictPolicy
+}
+
+func (opts CopyOptions) rewriteKey(key []byte) []byte {
+	if opts.FromPrefix == nil && opts.ToPrefix == nil || !bytes.HasPrefix(key, opts.FromPrefix) {
+		return key
+	}
+	rewritten := make([]byte, 0, len(opts.ToPrefix)+len(key)-len(opts.FromPrefix))
+	rewritten = append(rewritten, opts.ToPrefix...)
+	return append(rewritten, key[len(opts.FromPrefix):]...)
+}
+
+// Copy streams every record from the Badger at srcDir into the Badger at dstDir using the same batching as
+// WriteStream. The source must exist. The destination is created if needed and may already contain records, which
+// allows merging several Badgers into one.
+func Copy(srcDir, dstDir string, opts CopyOptions) error {
+	if sameDir(srcDir, dstDir) {
+		return fmt.Errorf("source and destination are both %v", srcDir)
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+
+	src, err := openExistingDB(srcDir)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if mkdirErr := os.MkdirAll(dstDir, os.ModePerm); mkdirErr != nil {
+		return mkdirErr
+	}
+
+	dst, err := openDB(dstDir)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	start := time.Now()
+
+	w := newBatchWriter(&BadgerSink{DB: dst, Conflict: opts.Conflict}, opts.BatchSize)
+
+	prefixes := opts.Prefixes
+	if len(prefixes) == 0 {
+		prefixes = [][]byte{nil}
+	}
+
+	err = src.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for _, prefix := range prefixes {
+			for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+				item := it.Item()
+				value, err := item.ValueCopy(nil)
+				if err != nil {
+					return err
+				}
+				kv := &KeyValue{Key: opts.rewriteKey(item.KeyCopy(nil)), Value: value}
+				if opts.Transform != nil {
+					if kv, err = opts.Transform(kv); err != nil {
+						return err
+					}
+					if kv == nil {
+						continue
+					}
+				}
+				w.add(*kv)
+			}
+		}
+		return nil
+	})
+
+	// Write remaining key/values even if reading failed so no transactions are left running
+	if flushErr := w.flush(); flushErr != nil {
+		return flushErr
+	}
+	if err != nil {
+		return err
+	}
+
+	DefaultLogger.Log(LevelInfo, "Copied records", "records", w.kvCount.get(), "elapsed", time.Since(start))
+	return nil
+}
+
+func sameDir(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
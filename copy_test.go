@@ -0,0 +1,86 @@
+package badgerutils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopy(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	srcPath := path.Join(tmpDir, "src")
+	dstPath := path.Join(tmpDir, "dst")
+
+	err = WriteStream(strings.NewReader("us/key1:value1\nus/key2:value2\nus/key3:drop\neu/key1:value3"), srcPath, 2, csvToKeyValue)
+	require.Nil(t, err)
+	err = WriteStream(strings.NewReader("west/key1:existing"), dstPath, 2, csvToKeyValue)
+	require.Nil(t, err)
+
+	err = Copy(srcPath, dstPath, CopyOptions{
+		Prefixes:   [][]byte{[]byte("us/")},
+		FromPrefix: []byte("us/"),
+		ToPrefix:   []byte("west/"),
+		Transform: func(kv *KeyValue) (*KeyValue, error) {
+			if bytes.Equal(kv.Value, []byte("drop")) {
+				return nil, nil
+			}
+			return kv, nil
+		},
+		Conflict: ConflictSkip,
+	})
+	require.Nil(t, err)
+
+	records, err := readDB(dstPath)
+	require.Nil(t, err)
+	require.Equal(t, []sampleRecord{
+		{Key: "west/key1", Value: "existing"},
+		{Key: "west/key2", Value: "value2"},
+	}, records)
+
+	// Batches whose keys all exist in the destination are skipped whole
+	copied := make(chan error, 1)
+	go func() {
+		copied <- Copy(srcPath, dstPath, CopyOptions{
+			BatchSize:  1,
+			Prefixes:   [][]byte{[]byte("us/key1"), []byte("us/key2")},
+			FromPrefix: []byte("us/"),
+			ToPrefix:   []byte("west/"),
+			Conflict:   ConflictSkip,
+		})
+	}()
+	select {
+	case err = <-copied:
+		require.Nil(t, err)
+	case <-time.After(30 * time.Second):
+		t.Fatal("copy of skipped batches did not return")
+	}
+
+	err = Copy(srcPath, dstPath, CopyOptions{
+		Prefixes:   [][]byte{[]byte("us/key1")},
+		FromPrefix: []byte("us/"),
+		ToPrefix:   []byte("west/"),
+		Conflict:   ConflictError,
+	})
+	require.NotNil(t, err)
+
+	err = Copy(srcPath, srcPath, CopyOptions{})
+	require.NotNil(t, err)
+
+	// A missing source fails instead of copying an empty DB
+	missing := path.Join(tmpDir, "missing")
+	err = Copy(missing, dstPath, CopyOptions{})
+	require.EqualError(t, err, "no DB directory at "+missing)
+	_, err = os.Stat(missing)
+	require.True(t, os.IsNotExist(err))
+}
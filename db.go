@@ -1,15 +1,47 @@
 package badgerutils
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/dgraph-io/badger"
 	"github.com/dgraph-io/badger/options"
 )
 
-func openDB(dir string) (*badger.DB, error) {
+// DefaultOptions are the Badger options used to open every DB. Dir and ValueDir are set from the directory
+// passed to each function.
+var DefaultOptions = defaultOptions()
+
+func defaultOptions() badger.Options {
 	opts := badger.DefaultOptions
 	opts.ValueLogLoadingMode = options.FileIO
 	opts.TableLoadingMode = options.FileIO
+	return opts
+}
+
+func openDB(dir string) (*badger.DB, error) {
+	opts := DefaultOptions
 	opts.Dir = dir
 	opts.ValueDir = dir
 	return badger.Open(opts)
 }
+
+// openExistingDB opens the Badger at dir like openDB, but fails instead of creating an empty DB when dir does not
+// exist, for functions that only read it.
+func openExistingDB(dir string) (*badger.DB, error) {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("no DB directory at %v", dir)
+	}
+	return openDB(dir)
+}
+
+// Open opens the Badger at dir with DefaultOptions. The directory is created unless DefaultOptions is read-only.
+func Open(dir string) (*badger.DB, error) {
+	if !DefaultOptions.ReadOnly {
+		if mkdirErr := os.MkdirAll(dir, os.ModePerm); mkdirErr != nil {
+			return nil, mkdirErr
+		}
+	}
+	return openDB(dir)
+}
@@ -0,0 +1,141 @@
+package badgerutils
+
+import (
+	"bytes"
+
+	"github.com/dgraph-io/badger"
+)
+
+// DiffKind describes how a key differs between two Badgers.
+type DiffKind string
+
+const (
+	// DiffOnlyInA is a key that only exists in the first Badger.
+	DiffOnlyInA DiffKind = "only_in_a"
+	// DiffOnlyInB is a key that only exists in the second Badger.
+	DiffOnlyInB DiffKind = "only_in_b"
+	// DiffChanged is a key that exists in both Badgers with different values.
+	DiffChanged DiffKind = "changed"
+)
+
+// Difference is a single key that differs between two Badgers. Keys and values are base64 encoded in JSON.
+type Difference struct {
+	Kind   DiffKind `json:"kind"`
+	Key    []byte   `json:"key"`
+	ValueA []byte   `json:"value_a,omitempty"`
+	ValueB []byte   `json:"value_b,omitempty"`
+}
+
+// DiffSummary counts the differences between two Badgers.
+type DiffSummary struct {
+	OnlyInA int `json:"only_in_a"`
+	OnlyInB int `json:"only_in_b"`
+	Changed int `json:"changed"`
+	Equal   int `json:"equal"`
+}
+
+// Identical returns true if no differences were found.
+func (s *DiffSummary) Identical() bool {
+	return s.OnlyInA == 0 && s.OnlyInB == 0 && s.Changed == 0
+}
+
+// Diff merge-iterates the keys with the given prefix in the Badgers at dirA and dirB in key order.
+// fn function parameter is called for each difference and may be nil when only the summary is needed.
+// Both directories must exist.
+func Diff(dirA, dirB string, prefix []byte, fn func(*Difference) error) (*DiffSummary, error) {
+	dbA, err := openExistingDB(dirA)
+	if err != nil {
+		return nil, err
+	}
+	defer dbA.Close()
+
+	dbB, err := openExistingDB(dirB)
+	if err != nil {
+		return nil, err
+	}
+	defer dbB.Close()
+
+	txnA := dbA.NewTransaction(false)
+	defer txnA.Discard()
+	txnB := dbB.NewTransaction(false)
+	defer txnB.Discard()
+
+	itA := txnA.NewIterator(badger.DefaultIteratorOptions)
+	defer itA.Close()
+	itB := txnB.NewIterator(badger.DefaultIteratorOptions)
+	defer itB.Close()
+
+	summary := &DiffSummary{}
+	report := func(d *Difference) error {
+		switch d.Kind {
+		case DiffOnlyInA:
+			summary.OnlyInA++
+		case DiffOnlyInB:
+			summary.OnlyInB++
+		case DiffChanged:
+			summary.Changed++
+		}
+		if fn == nil {
+			return nil
+		}
+		return fn(d)
+	}
+
+	itA.Seek(prefix)
+	itB.Seek(prefix)
+	for {
+		validA, validB := itA.ValidForPrefix(prefix), itB.ValidForPrefix(prefix)
+		if !validA && !validB {
+			break
+		}
+
+		cmp := 0
+		switch {
+		case !validB:
+			cmp = -1
+		case !validA:
+			cmp = 1
+		default:
+			cmp = bytes.Compare(itA.Item().Key(), itB.Item().Key())
+		}
+
+		switch {
+		case cmp < 0:
+			valueA, err := itA.Item().ValueCopy(nil)
+			if err != nil {
+				return nil, err
+			}
+			if err := report(&Difference{Kind: DiffOnlyInA, Key: itA.Item().KeyCopy(nil), ValueA: valueA}); err != nil {
+				return nil, err
+			}
+			itA.Next()
+		case cmp > 0:
+			valueB, err := itB.Item().ValueCopy(nil)
+			if err != nil {
+				return nil, err
+			}
+			if err := report(&Difference{Kind: DiffOnlyInB, Key: itB.Item().KeyCopy(nil), ValueB: valueB}); err != nil {
+				return nil, err
+			}
+			itB.Next()
+		default:
+			valueA, err := itA.Item().ValueCopy(nil)
+			if err != nil {
+				return nil, err
+			}
+			valueB, err := itB.Item().ValueCopy(nil)
+			if err != nil {
+				return nil, err
+			}
+			if bytes.Equal(valueA, valueB) {
+				summary.Equal++
+			} else if err := report(&Difference{Kind: DiffChanged, Key: itA.Item().KeyCopy(nil), ValueA: valueA, ValueB: valueB}); err != nil {
+				return nil, err
+			}
+			itA.Next()
+			itB.Next()
+		}
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,55 @@
+package badgerutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPathA := path.Join(tmpDir, "a")
+	dbPathB := path.Join(tmpDir, "b")
+
+	err = WriteStream(strings.NewReader("key1:value1\nkey2:value2\nkey3:value3\nother:value"), dbPathA, 2, csvToKeyValue)
+	require.Nil(t, err)
+	err = WriteStream(strings.NewReader("key2:value2\nkey3:changed\nkey4:value4\nother:value"), dbPathB, 2, csvToKeyValue)
+	require.Nil(t, err)
+
+	differences := make([]Difference, 0)
+	summary, err := Diff(dbPathA, dbPathB, []byte("key"), func(d *Difference) error {
+		differences = append(differences, *d)
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, DiffSummary{OnlyInA: 1, OnlyInB: 1, Changed: 1, Equal: 1}, *summary)
+	require.False(t, summary.Identical())
+	require.Equal(t, []Difference{
+		{Kind: DiffOnlyInA, Key: []byte("key1"), ValueA: []byte("value1")},
+		{Kind: DiffChanged, Key: []byte("key3"), ValueA: []byte("value3"), ValueB: []byte("changed")},
+		{Kind: DiffOnlyInB, Key: []byte("key4"), ValueB: []byte("value4")},
+	}, differences)
+
+	summary, err = Diff(dbPathA, dbPathB, []byte("other"), nil)
+	require.Nil(t, err)
+	require.True(t, summary.Identical())
+	require.Equal(t, 1, summary.Equal)
+
+	// A missing directory is an error rather than an empty DB
+	missing := path.Join(tmpDir, "missing")
+	_, err = Diff(dbPathA, missing, nil, nil)
+	require.EqualError(t, err, "no DB directory at "+missing)
+	_, err = Diff(missing, dbPathB, nil, nil)
+	require.NotNil(t, err)
+	_, err = os.Stat(missing)
+	require.True(t, os.IsNotExist(err))
+}
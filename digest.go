@@ -0,0 +1,144 @@
+package badgerutils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"math/bits"
+	"runtime"
+	"sync"
+
+	"github.com/dgraph-io/badger"
+)
+
+// DBDigest is a deterministic, order-independent checksum over a set of key/value pairs.
+type DBDigest struct {
+	Count    int    `json:"count"`
+	Checksum string `json:"checksum"`
+}
+
+// digestSum accumulates the SHA-256 of every key/value pair as a 256-bit sum so the result does not depend on
+// the order in which pairs are added.
+type digestSum struct {
+	count int
+	sum   [4]uint64
+}
+
+func (d *digestSum) add(key, value []byte) {
+	h := sha256.New()
+	binary.Write(h, binary.BigEndian, uint64(len(key)))
+	h.Write(key)
+	h.Write(value)
+	d.addHash(h.Sum(nil))
+	d.count++
+}
+
+func (d *digestSum) addHash(hash []byte) {
+	var carry uint64
+	for i := 3; i >= 0; i-- {
+		d.sum[i], carry = bits.Add64(d.sum[i], binary.BigEndian.Uint64(hash[i*8:]), carry)
+	}
+}
+
+func (d *digestSum) merge(other *digestSum) {
+	var buf [32]byte
+	for i := range other.sum {
+		binary.BigEndian.PutUint64(buf[i*8:], other.sum[i])
+	}
+	d.addHash(buf[:])
+	d.count += other.count
+}
+
+func (d *digestSum) digest() *DBDigest {
+	var buf [32]byte
+	for i := range d.sum {
+		binary.BigEndian.PutUint64(buf[i*8:], d.sum[i])
+	}
+	return &DBDigest{Count: d.count, Checksum: hex.EncodeToString(buf[:])}
+}
+
+func digestRange(txn *badger.Txn, r keyRange, prefix []byte, d *digestSum) error {
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+
+	seek := r.Start
+	if bytes.Compare(prefix, seek) > 0 {
+		seek = prefix
+	}
+	for it.Seek(seek); it.ValidForPrefix(prefix); it.Next() {
+		item := it.Item()
+		if !r.contains(item.Key()) {
+			break
+		}
+		value, err := item.Value()
+		if err != nil {
+			return err
+		}
+		d.add(item.Key(), value)
+	}
+	return nil
+}
+
+// Digest computes a DBDigest over all live key/value pairs with the given prefix in the Badger at dir.
+// Key ranges are digested in parallel from the same snapshot. A missing dir is an error, not an empty digest.
+func Digest(dir string, prefix []byte) (*DBDigest, error) {
+	db, err := openExistingDB(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	ranges := partitionRanges(splitKeys(tableRanges(db, dir), runtime.NumCPU()))
+
+	// All read transactions are created before iteration starts so they share one snapshot timestamp.
+	txns := make([]*badger.Txn, len(ranges))
+	for i := range ranges {
+		txns[i] = db.NewTransaction(false)
+		defer txns[i].Discard()
+	}
+
+	var wg sync.WaitGroup
+	sums := make([]digestSum, len(ranges))
+	errs := make([]error, len(ranges))
+	for i, r := range ranges {
+		wg.Add(1)
+		go func(i int, r keyRange) {
+			defer wg.Done()
+			errs[i] = digestRange(txns[i], r, prefix, &sums[i])
+		}(i, r)
+	}
+	wg.Wait()
+
+	total := &digestSum{}
+	for i := range ranges {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		total.merge(&sums[i])
+	}
+	return total.digest(), nil
+}
+
+// DigestStream computes a DBDigest over the key/value pairs with the given prefix parsed from reader with
+// lineToKeyValue. It matches the Digest of a Badger written by WriteStream from the same input as long as the
+// input has no duplicate keys.
+func DigestStream(reader io.Reader, prefix []byte, lineToKeyValue func(string) (*KeyValue, error)) (*DBDigest, error) {
+	d := &digestSum{}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		kv, err := lineToKeyValue(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(kv.Key, prefix) {
+			d.add(kv.Key, kv.Value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return d.digest(), nil
+}
@@ -0,0 +1,51 @@
+package badgerutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigest(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := path.Join(tmpDir, "db")
+
+	input := "key1:value1\nkey2:value2\nkey3:value3\nother:value"
+	err = WriteStream(strings.NewReader(input), dbPath, 2, csvToKeyValue)
+	require.Nil(t, err)
+
+	dbDigest, err := Digest(dbPath, nil)
+	require.Nil(t, err)
+	require.Equal(t, 4, dbDigest.Count)
+
+	// Input order does not change the digest
+	reordered := "other:value\nkey3:value3\nkey1:value1\nkey2:value2"
+	streamDigest, err := DigestStream(strings.NewReader(reordered), nil, csvToKeyValue)
+	require.Nil(t, err)
+	require.Equal(t, dbDigest, streamDigest)
+
+	prefixDigest, err := Digest(dbPath, []byte("key"))
+	require.Nil(t, err)
+	require.Equal(t, 3, prefixDigest.Count)
+	require.NotEqual(t, dbDigest.Checksum, prefixDigest.Checksum)
+
+	corrupted := "key1:value1\nkey2:value2\nkey3:changed\nother:value"
+	streamDigest, err = DigestStream(strings.NewReader(corrupted), nil, csvToKeyValue)
+	require.Nil(t, err)
+	require.NotEqual(t, dbDigest.Checksum, streamDigest.Checksum)
+
+	missing := path.Join(tmpDir, "missing")
+	_, err = Digest(missing, nil)
+	require.EqualError(t, err, "no DB directory at "+missing)
+	_, err = os.Stat(missing)
+	require.True(t, os.IsNotExist(err))
+}
@@ -37,6 +37,8 @@ func main() {
 		log.Fatal(errors.New("dir flag is required"))
 	}
 
+	badgerutils.DefaultLogger = badgerutils.NewStdLogger(log.New(os.Stderr, "", log.LstdFlags), badgerutils.LevelDebug)
+
 	log.Printf("Directory: %v", *dir)
 	log.Printf("Batch Size: %v", *batchSize)
 
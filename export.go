@@ -0,0 +1,318 @@
+package badgerutils
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger"
+	"github.com/dgraph-io/badger/table"
+	"github.com/dgraph-io/badger/y"
+)
+
+// keyRange defines a half-open [Start, End) range of keys. A nil End means the range is unbounded.
+type keyRange struct {
+	Start []byte
+	End   []byte
+}
+
+func (r keyRange) contains(key []byte) bool {
+	return bytes.Compare(key, r.Start) >= 0 && (r.End == nil || bytes.Compare(key, r.End) < 0)
+}
+
+// tableRange is the key span and on-disk size of a single LSM table.
+type tableRange struct {
+	Left  []byte
+	Right []byte
+	Size  int64
+}
+
+func tableRanges(db *badger.DB, dir string) []tableRange {
+	tables := db.Tables()
+	ranges := make([]tableRange, 0, len(tables))
+	for _, t := range tables {
+		// Tables whose file cannot be read are still counted so that the split keys remain usable.
+		size := int64(1)
+		if fi, err := os.Stat(table.NewFilename(t.ID, dir)); err == nil && fi.Size() > 0 {
+			size = fi.Size()
+		}
+		ranges = append(ranges, tableRange{
+			Left:  y.ParseKey(t.Left),
+			Right: y.ParseKey(t.Right),
+			Size:  size,
+		})
+	}
+	return ranges
+}
+
+// splitKeys computes up to n-1 split keys that divide the tables into ranges of roughly even size.
+func splitKeys(tables []tableRange, n int) [][]byte {
+	if n < 2 || len(tables) == 0 {
+		return nil
+	}
+
+	sorted := make([]tableRange, len(tables))
+	copy(sorted, tables)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Right, sorted[j].Right) < 0
+	})
+
+	var total int64
+	for _, t := range sorted {
+		total += t.Size
+	}
+
+	splits := make([][]byte, 0, n-1)
+	var acc int64
+	for _, t := range sorted {
+		acc += t.Size
+		if len(splits) == n-1 {
+			break
+		}
+		if acc*int64(n) < total*int64(len(splits)+1) {
+			continue
+		}
+		if len(splits) > 0 && bytes.Compare(t.Right, splits[len(splits)-1]) <= 0 {
+			continue
+		}
+		// Split keys start the next partition, so the table's last key is moved past by one byte.
+		splits = append(splits, append(y.Copy(t.Right), 0))
+	}
+	return splits
+}
+
+func partitionRanges(splits [][]byte) []keyRange {
+	ranges := make([]keyRange, 0, len(splits)+1)
+	var start []byte
+	for _, split := range splits {
+		ranges = append(ranges, keyRange{Start: start, End: split})
+		start = split
+	}
+	return append(ranges, keyRange{Start: start})
+}
+
+func exportRange(txn *badger.Txn, r keyRange, writer io.Writer, keyValueToLine func(*KeyValue) (string, error)) (int, error) {
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+
+	count := 0
+	for it.Seek(r.Start); it.Valid(); it.Next() {
+		item := it.Item()
+		if !r.contains(item.Key()) {
+			break
+		}
+		value, err := item.ValueCopy(nil)
+		if err != nil {
+			return count, err
+		}
+		line, err := keyValueToLine(&KeyValue{Key: item.KeyCopy(nil), Value: value})
+		if err != nil {
+			return count, err
+		}
+		if _, err := fmt.Fprintln(writer, line); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// exportPartitions splits the Badger at dir into partitions and iterates them concurrently, writing each
+// partition to its writer from the writers returned by partitionWriters for the number of partitions.
+func exportPartitions(dir string, partitions int, partitionWriters func(int) ([]io.WriteCloser, error),
+	keyValueToLine func(*KeyValue) (string, error)) (int, error) {
+	db, err := openDB(dir)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	ranges := partitionRanges(splitKeys(tableRanges(db, dir), partitions))
+	writers, err := partitionWriters(len(ranges))
+	if err != nil {
+		return 0, err
+	}
+
+	// All read transactions are created before iteration starts so they share one snapshot timestamp.
+	txns := make([]*badger.Txn, len(ranges))
+	for i := range ranges {
+		txns[i] = db.NewTransaction(false)
+		defer txns[i].Discard()
+	}
+
+	var wg sync.WaitGroup
+	counts := make([]int, len(ranges))
+	errs := make([]error, len(ranges))
+	for i, r := range ranges {
+		wg.Add(1)
+		go func(i int, r keyRange) {
+			defer wg.Done()
+			w := writers[i]
+			bw := bufio.NewWriterSize(w, exportChunkSize)
+			counts[i], errs[i] = exportRange(txns[i], r, bw, keyValueToLine)
+			if err := bw.Flush(); err != nil && errs[i] == nil {
+				errs[i] = err
+			}
+			if err := w.Close(); err != nil && errs[i] == nil {
+				errs[i] = err
+			}
+		}(i, r)
+	}
+	wg.Wait()
+
+	// Partitions stopped by errExportAborted report the error that aborted the export instead
+	total := 0
+	var firstErr error
+	for i := range ranges {
+		if errs[i] != nil && (firstErr == nil || firstErr == errExportAborted) {
+			firstErr = errs[i]
+		}
+		total += counts[i]
+	}
+	return total, firstErr
+}
+
+func partitionFilename(dir string, i int) string {
+	return path.Join(dir, fmt.Sprintf("part-%05d", i))
+}
+
+// exportChunkSize is the size of the writes of each partition, and exportReadAhead the number of chunks a partition
+// is read ahead of the partition being written by ExportStream.
+const (
+	exportChunkSize = 64 * 1024
+	exportReadAhead = 16
+)
+
+// errExportAborted stops the partitions of ExportStream once writing to its writer failed.
+var errExportAborted = errors.New("export aborted")
+
+// chunkWriter sends a copy of each write to chunks, blocking while chunks is full.
+type chunkWriter struct {
+	chunks  chan []byte
+	aborted <-chan struct{}
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	select {
+	case w.chunks <- append([]byte{}, p...):
+		return len(p), nil
+	case <-w.aborted:
+		return 0, errExportAborted
+	}
+}
+
+func (w *chunkWriter) Close() error {
+	close(w.chunks)
+	return nil
+}
+
+// ExportStream writes every key/value pair in the Badger at dir to writer in key order.
+// The key space is split into roughly even partitions from the table boundaries which are iterated
+// concurrently. Partitions are written to writer in order as they are read, and each partition is read ahead of
+// the one being written by a bounded number of chunks, so the export needs no temporary space.
+// keyValueToLine function parameter defines how a key/value pair is translated to a line.
+func ExportStream(writer io.Writer, dir string, partitions int, keyValueToLine func(*KeyValue) (string, error)) error {
+	start := time.Now()
+
+	aborted := make(chan struct{})
+	var abortOnce sync.Once
+	abort := func() { abortOnce.Do(func() { close(aborted) }) }
+	var written chan struct{}
+	var writeErr error
+	partitionWriters := func(n int) ([]io.WriteCloser, error) {
+		chunkWriters := make([]*chunkWriter, n)
+		writers := make([]io.WriteCloser, n)
+		for i := range writers {
+			chunkWriters[i] = &chunkWriter{chunks: make(chan []byte, exportReadAhead), aborted: aborted}
+			writers[i] = chunkWriters[i]
+		}
+
+		written = make(chan struct{})
+		go func() {
+			defer close(written)
+			for _, w := range chunkWriters {
+				// Chunks are drained after the export is aborted so that every partition completes
+				for chunk := range w.chunks {
+					select {
+					case <-aborted:
+						continue
+					default:
+					}
+					if _, err := writer.Write(chunk); err != nil {
+						writeErr = err
+						abort()
+					}
+				}
+			}
+		}()
+		return writers, nil
+	}
+	toLine := func(kv *KeyValue) (string, error) {
+		line, err := keyValueToLine(kv)
+		if err != nil {
+			abort()
+		}
+		return line, err
+	}
+
+	// Every partition writer is closed by exportPartitions, so the writes end once it returns
+	count, err := exportPartitions(dir, partitions, partitionWriters, toLine)
+	if err != nil {
+		abort()
+	}
+	if written != nil {
+		<-written
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	DefaultLogger.Log(LevelInfo, "Exported records", "records", count, "elapsed", time.Since(start))
+	return nil
+}
+
+// ExportFiles writes every key/value pair in the Badger at dir into outDir with one file per partition.
+// Each file is in key order and the files sort in key order by name. It returns the written file paths.
+func ExportFiles(outDir string, dir string, partitions int, keyValueToLine func(*KeyValue) (string, error)) ([]string, error) {
+	if err := os.MkdirAll(outDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	files := make([]string, 0)
+	partitionWriters := func(n int) ([]io.WriteCloser, error) {
+		writers := make([]io.WriteCloser, 0, n)
+		for i := 0; i < n; i++ {
+			filename := partitionFilename(outDir, i)
+			f, err := os.Create(filename)
+			if err != nil {
+				for _, w := range writers {
+					w.Close()
+				}
+				return nil, err
+			}
+			files = append(files, filename)
+			writers = append(writers, f)
+		}
+		return writers, nil
+	}
+
+	count, err := exportPartitions(dir, partitions, partitionWriters, keyValueToLine)
+	if err != nil {
+		return nil, err
+	}
+
+	DefaultLogger.Log(LevelInfo, "Exported records", "records", count, "files", len(files), "elapsed", time.Since(start))
+	return files, nil
+}
@@ -0,0 +1,153 @@
+package badgerutils
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func keyValueToCsv(kv *KeyValue) (string, error) {
+	return fmt.Sprintf("%s:%s", kv.Key, kv.Value), nil
+}
+
+func TestSplitKeys(t *testing.T) {
+	tables := []tableRange{
+		{Left: []byte("a"), Right: []byte("c"), Size: 10},
+		{Left: []byte("d"), Right: []byte("f"), Size: 10},
+		{Left: []byte("g"), Right: []byte("i"), Size: 10},
+		{Left: []byte("j"), Right: []byte("l"), Size: 10},
+	}
+
+	splits := splitKeys(tables, 2)
+	require.Equal(t, [][]byte{[]byte("f\x00")}, splits)
+
+	splits = splitKeys(tables, 4)
+	require.Equal(t, [][]byte{[]byte("c\x00"), []byte("f\x00"), []byte("i\x00")}, splits)
+
+	require.Nil(t, splitKeys(tables, 1))
+	require.Nil(t, splitKeys(nil, 4))
+
+	ranges := partitionRanges(splits)
+	require.Equal(t, 4, len(ranges))
+	require.True(t, ranges[0].contains([]byte("a")))
+	require.False(t, ranges[0].contains([]byte("d")))
+	require.True(t, ranges[3].contains([]byte("z")))
+}
+
+func TestExport(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := path.Join(tmpDir, "db")
+
+	input := `key1:value1
+key2:value2
+key3:value3`
+	err = WriteStream(strings.NewReader(input), dbPath, 2, csvToKeyValue)
+	require.Nil(t, err)
+
+	var buf bytes.Buffer
+	err = ExportStream(&buf, dbPath, 4, keyValueToCsv)
+	require.Nil(t, err)
+	require.Equal(t, input+"\n", buf.String())
+
+	files, err := ExportFiles(path.Join(tmpDir, "export"), dbPath, 4, keyValueToCsv)
+	require.Nil(t, err)
+	require.NotEmpty(t, files)
+
+	var exported string
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		require.Nil(t, err)
+		exported += string(b)
+	}
+	require.Equal(t, input+"\n", exported)
+}
+
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.writes > 2 {
+		return 0, fmt.Errorf("disk full")
+	}
+	return len(p), nil
+}
+
+func TestExportPartitions(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	// Small tables spread the keys over several tables so that the export is split into partitions
+	defaultOptions := DefaultOptions
+	defer func() { DefaultOptions = defaultOptions }()
+	DefaultOptions.MaxTableSize = 64 << 10
+	DefaultOptions.LevelOneSize = 256 << 10
+
+	dbPath := path.Join(tmpDir, "db")
+	var input bytes.Buffer
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&input, "key%06d:%v\n", i, strings.Repeat("v", 50))
+	}
+	err = WriteStream(bytes.NewReader(input.Bytes()), dbPath, 100, csvToKeyValue)
+	require.Nil(t, err)
+
+	files, err := ExportFiles(path.Join(tmpDir, "export"), dbPath, 4, keyValueToCsv)
+	require.Nil(t, err)
+	require.True(t, len(files) > 1, "expected several partitions, got %v", len(files))
+
+	var buf bytes.Buffer
+	err = ExportStream(&buf, dbPath, 4, keyValueToCsv)
+	require.Nil(t, err)
+	require.Equal(t, input.String(), buf.String())
+
+	err = ExportStream(&failingWriter{}, dbPath, 4, keyValueToCsv)
+	require.EqualError(t, err, "disk full")
+
+	// A failed partition stops the export, and nothing is written once ExportStream returned
+	writer := &countingWriter{}
+	err = ExportStream(writer, dbPath, 4, func(kv *KeyValue) (string, error) {
+		if string(kv.Key) == "key015000" {
+			return "", fmt.Errorf("bad key")
+		}
+		return keyValueToCsv(kv)
+	})
+	require.EqualError(t, err, "bad key")
+	writes := writer.count()
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, writes, writer.count())
+}
+
+type countingWriter struct {
+	mu     sync.Mutex
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes++
+	return len(p), nil
+}
+
+func (w *countingWriter) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writes
+}
@@ -0,0 +1,167 @@
+package geo
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Surfline/badgerutils"
+	"github.com/Surfline/badgerutils/badgerutilstest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	require.Equal(t, "ezs42", Encode(Point{Lat: 42.6, Lon: -5.6}, 5))
+	require.Equal(t, "u4pruydqqvj", Encode(Point{Lat: 57.64911, Lon: 10.40744}, 11))
+	require.Equal(t, MaxPrecision, len(Encode(Point{}, 0)))
+
+	p := Point{Lat: 33.655, Lon: -118.005}
+	box, err := Decode(Encode(p, MaxPrecision))
+	require.Nil(t, err)
+	require.True(t, box.Contains(p))
+	require.InDelta(t, 0, Distance(p, box.Center()), 0.1)
+	require.InDelta(t, 0, Distance(p, FromZOrder(ZOrder(p)).Center()), 0.1)
+
+	_, err = Decode("ezs4a")
+	require.NotNil(t, err)
+}
+
+func TestDistance(t *testing.T) {
+	require.InDelta(t, 111195, Distance(Point{Lat: 10, Lon: 20}, Point{Lat: 11, Lon: 20}), 1)
+	require.InDelta(t, 111195, Distance(Point{Lat: 0, Lon: 179.5}, Point{Lat: 0, Lon: -179.5}), 1)
+
+	box := RadiusBox(Point{Lat: 0, Lon: 179.9}, 50000)
+	require.True(t, box.MinLon > box.MaxLon)
+	require.True(t, box.Contains(Point{Lat: 0, Lon: -179.9}))
+	require.Equal(t, Box{MinLat: 89.5 - degrees(1e5/EarthRadius), MinLon: -180, MaxLat: 90, MaxLon: 180},
+		RadiusBox(Point{Lat: 89.5, Lon: 0}, 1e5))
+}
+
+func TestQueries(t *testing.T) {
+	db := badgerutilstest.TempDB(t)
+	indexes := []Index{
+		{Prefix: []byte("g/"), Encoding: EncodingGeohash},
+		{Prefix: []byte("z/"), Encoding: EncodingZOrder, MaxRanges: 8},
+	}
+
+	points := make(map[string]Point)
+	for lat := -80.0; lat <= 80; lat += 2.5 {
+		for lon := -180.0; lon < 180; lon += 2.5 {
+			points[fmt.Sprintf("grid-%v/%v", lat, lon)] = Point{Lat: lat, Lon: lon}
+		}
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		points[fmt.Sprintf("spot-%03d", i)] = Point{Lat: 33.6 + r.Float64()*0.1, Lon: -118.05 + r.Float64()*0.1}
+	}
+
+	lines := make([]string, 0, len(points))
+	for id, p := range points {
+		lines = append(lines, fmt.Sprintf("%v,%v,%v", id, p.Lat, p.Lon))
+	}
+	for _, ix := range indexes {
+		parse := ix.Parser(func(line string) (Point, []byte, []byte, error) {
+			fields := strings.Split(line, ",")
+			lat, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				return Point{}, nil, nil, err
+			}
+			lon, err := strconv.ParseFloat(fields[2], 64)
+			if err != nil {
+				return Point{}, nil, nil, err
+			}
+			return Point{Lat: lat, Lon: lon}, []byte(fields[0]), []byte(fields[0]), nil
+		})
+		result, err := badgerutils.WriteStreamTo(context.Background(), strings.NewReader(strings.Join(lines, "\n")),
+			badgerutils.NewBadgerSink(db), 1000, parse, nil)
+		require.Nil(t, err)
+		require.Equal(t, len(points), result.Records)
+
+		_, err = parse("bad,95,0")
+		require.EqualError(t, err, "invalid location 95,0")
+	}
+
+	expected := func(match func(Point) bool) []string {
+		ids := make([]string, 0)
+		for id, p := range points {
+			if match(p) {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		return ids
+	}
+	// fn runs on the scan goroutines, so hits are collected and checked once the query returns
+	collect := func(query func(func(*Hit) error) error) ([]*Hit, []string) {
+		hits := make([]*Hit, 0)
+		require.Nil(t, query(func(hit *Hit) error {
+			hits = append(hits, hit)
+			return nil
+		}))
+		ids := make([]string, 0, len(hits))
+		for _, hit := range hits {
+			require.Equal(t, string(hit.ID), string(hit.Value))
+			ids = append(ids, string(hit.ID))
+		}
+		sort.Strings(ids)
+		return hits, ids
+	}
+
+	boxes := []Box{
+		{MinLat: 33.62, MinLon: -118.02, MaxLat: 33.66, MaxLon: -117.98},
+		{MinLat: -9.9, MinLon: 170.1, MaxLat: 9.9, MaxLon: -170.1},
+		{MinLat: -90, MinLon: -180, MaxLat: 90, MaxLon: 180},
+	}
+	for _, ix := range indexes {
+		for _, box := range boxes {
+			want := expected(box.Contains)
+			require.NotEmpty(t, want)
+			_, got := collect(func(fn func(*Hit) error) error { return ix.Within(db, box, fn) })
+			require.Equal(t, want, got)
+		}
+
+		center := Point{Lat: 33.65, Lon: -118}
+		for _, radius := range []float64{500, 3000, 500000} {
+			want := expected(func(p Point) bool { return Distance(center, p) <= radius })
+			require.NotEmpty(t, want)
+			hits, got := collect(func(fn func(*Hit) error) error { return ix.Near(db, center, radius, fn) })
+			require.Equal(t, want, got)
+			for _, hit := range hits {
+				require.True(t, hit.Distance <= radius)
+			}
+		}
+
+		// The antimeridian box is scanned as several parallel ranges, which all stop at the first error
+		require.True(t, len(ix.Cover(boxes[1])) > 1)
+		calls := 0
+		err := ix.Within(db, boxes[1], func(*Hit) error {
+			calls++
+			return fmt.Errorf("stop")
+		})
+		require.EqualError(t, err, "stop")
+		require.Equal(t, 1, calls)
+	}
+}
+
+func TestCover(t *testing.T) {
+	for _, ix := range []Index{{Encoding: EncodingGeohash}, {Encoding: EncodingZOrder, Prefix: []byte("z")}} {
+		box := Box{MinLat: 33.6, MinLon: -118.1, MaxLat: 33.7, MaxLon: -118}
+		ranges := ix.Cover(box)
+		require.True(t, len(ranges) > 0 && len(ranges) <= 16)
+
+		r := rand.New(rand.NewSource(2))
+		for i := 0; i < 1000; i++ {
+			p := Point{Lat: box.MinLat + r.Float64()*0.1, Lon: box.MinLon + r.Float64()*0.1}
+			key := ix.Key(p, []byte("id"))
+			covered := false
+			for _, kr := range ranges {
+				covered = covered || kr.Contains(key)
+			}
+			require.True(t, covered, "%v not covered", p)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// Package geo encodes locations into Badger keys that keep nearby points close together, and answers bounding box
+// and radius queries by scanning the key ranges of the cells that cover them.
+package geo
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// EarthRadius is the mean radius of the Earth in meters used by Distance.
+const EarthRadius = 6371008.8
+
+// MaxPrecision is the number of characters of the longest geohash, which is the length of geohash keys.
+const MaxPrecision = 12
+
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Point is a location in degrees.
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Valid reports whether p is a latitude in [-90, 90] and a longitude in [-180, 180].
+func (p Point) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lon >= -180 && p.Lon <= 180
+}
+
+// Box is the area between two latitudes and two longitudes in degrees. A box with MinLon greater than MaxLon crosses
+// the antimeridian.
+type Box struct {
+	MinLat float64 `json:"minLat"`
+	MinLon float64 `json:"minLon"`
+	MaxLat float64 `json:"maxLat"`
+	MaxLon float64 `json:"maxLon"`
+}
+
+// Contains reports whether p is inside the box, edges included.
+func (b Box) Contains(p Point) bool {
+	if p.Lat < b.MinLat || p.Lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return p.Lon >= b.MinLon && p.Lon <= b.MaxLon
+	}
+	return p.Lon >= b.MinLon || p.Lon <= b.MaxLon
+}
+
+// Center returns the point in the middle of the box.
+func (b Box) Center() Point {
+	lon := (b.MinLon + b.MaxLon) / 2
+	if b.MinLon > b.MaxLon {
+		lon += 180
+		if lon > 180 {
+			lon -= 360
+		}
+	}
+	return Point{Lat: (b.MinLat + b.MaxLat) / 2, Lon: lon}
+}
+
+// Distance returns the great-circle distance between a and b in meters.
+func Distance(a, b Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat, dLon := lat2-lat1, radians(b.Lon-a.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// RadiusBox returns the smallest box containing every point within radius meters of center.
+func RadiusBox(center Point, radius float64) Box {
+	d := degrees(radius / EarthRadius)
+	box := Box{MinLat: center.Lat - d, MaxLat: center.Lat + d, MinLon: -180, MaxLon: 180}
+	if box.MinLat <= -90 || box.MaxLat >= 90 {
+		box.MinLat, box.MaxLat = math.Max(box.MinLat, -90), math.Min(box.MaxLat, 90)
+		return box
+	}
+
+	sin := math.Sin(radius/EarthRadius) / math.Cos(radians(center.Lat))
+	if sin >= 1 {
+		return box
+	}
+	dLon := degrees(math.Asin(sin))
+	box.MinLon, box.MaxLon = wrapLon(center.Lon-dLon), wrapLon(center.Lon+dLon)
+	return box
+}
+
+// Encode returns the geohash of p with precision characters, from 1 to MaxPrecision.
+func Encode(p Point, precision int) string {
+	if precision < 1 || precision > MaxPrecision {
+		precision = MaxPrecision
+	}
+	return geohash(zorder(p, uint(5*precision)), uint(5*precision))
+}
+
+// Decode returns the cell of a geohash.
+func Decode(hash string) (Box, error) {
+	code, err := parseGeohash(hash)
+	if err != nil {
+		return Box{}, err
+	}
+	return cell(code, uint(5*len(hash))), nil
+}
+
+// ZOrder returns the Z-order curve value of p: 32 bits of longitude interleaved with 32 bits of latitude, starting
+// with longitude like a geohash.
+func ZOrder(p Point) uint64 {
+	return zorder(p, 64)
+}
+
+// FromZOrder returns the cell of a Z-order curve value.
+func FromZOrder(z uint64) Box {
+	return cell(z, 64)
+}
+
+// zorder returns the first depth bits of the Z-order curve value of p, longitude first.
+func zorder(p Point, depth uint) uint64 {
+	lonBits, latBits := (depth+1)/2, depth/2
+	return interleave(quantize(p.Lon, -180, 180, lonBits), quantize(p.Lat, -90, 90, latBits), depth)
+}
+
+// quantize returns the index of the interval of v among the 2^bits equal intervals between min and max.
+func quantize(v, min, max float64, bits uint) uint64 {
+	if bits == 0 {
+		return 0
+	}
+	n := math.Ldexp(1, int(bits))
+	i := math.Floor((v - min) / (max - min) * n)
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return uint64(n - 1)
+	}
+	return uint64(i)
+}
+
+// interleave returns the depth bits alternating between lon and lat, most significant first and starting with lon.
+func interleave(lon, lat uint64, depth uint) uint64 {
+	lonBits, latBits := (depth+1)/2, depth/2
+	var code uint64
+	for i := uint(0); i < depth; i++ {
+		var bit uint64
+		if i%2 == 0 {
+			lonBits--
+			bit = lon >> lonBits & 1
+		} else {
+			latBits--
+			bit = lat >> latBits & 1
+		}
+		code = code<<1 | bit
+	}
+	return code
+}
+
+// deinterleave is the inverse of interleave.
+func deinterleave(code uint64, depth uint) (lon, lat uint64) {
+	for i := uint(0); i < depth; i++ {
+		bit := code >> (depth - 1 - i) & 1
+		if i%2 == 0 {
+			lon = lon<<1 | bit
+		} else {
+			lat = lat<<1 | bit
+		}
+	}
+	return lon, lat
+}
+
+// cell returns the box of the Z-order cell code of depth bits.
+func cell(code uint64, depth uint) Box {
+	lon, lat := deinterleave(code, depth)
+	lonSize := 360 / math.Ldexp(1, int((depth+1)/2))
+	latSize := 180 / math.Ldexp(1, int(depth/2))
+	return Box{
+		MinLat: -90 + float64(lat)*latSize,
+		MinLon: -180 + float64(lon)*lonSize,
+		MaxLat: -90 + float64(lat+1)*latSize,
+		MaxLon: -180 + float64(lon+1)*lonSize,
+	}
+}
+
+// geohash returns the base32 characters of a Z-order cell code whose depth is a multiple of 5.
+func geohash(code uint64, depth uint) string {
+	b := make([]byte, depth/5)
+	for i := range b {
+		b[i] = base32[code>>(depth-5*uint(i+1))&0x1F]
+	}
+	return string(b)
+}
+
+func parseGeohash(hash string) (uint64, error) {
+	if len(hash) == 0 || len(hash) > MaxPrecision {
+		return 0, fmt.Errorf("geohash %q must have 1 to %v characters", hash, MaxPrecision)
+	}
+	var code uint64
+	for _, c := range hash {
+		i := strings.IndexRune(base32, c)
+		if i < 0 {
+			return 0, fmt.Errorf("invalid geohash character %q in %q", c, hash)
+		}
+		code = code<<5 | uint64(i)
+	}
+	return code, nil
+}
+
+func wrapLon(lon float64) float64 {
+	if lon < -180 {
+		return lon + 360
+	}
+	if lon > 180 {
+		return lon - 360
+	}
+	return lon
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
@@ -0,0 +1,243 @@
+package geo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Surfline/badgerutils"
+	"github.com/Surfline/badgerutils/keys"
+	"github.com/dgraph-io/badger"
+)
+
+// Encoding is the layout of the location part of the keys of an Index.
+type Encoding int
+
+// Key encodings.
+const (
+	// EncodingGeohash encodes a location as its geohash of MaxPrecision characters, which keeps keys readable.
+	EncodingGeohash Encoding = iota
+	// EncodingZOrder encodes a location as the 8 big-endian bytes of its Z-order curve value, which keeps keys short.
+	EncodingZOrder
+)
+
+// errStop stops the scans of a query after fn fails.
+var errStop = errors.New("query stopped")
+
+// Index lays out the keys of located records as Prefix, the encoded location and an ID, so that the records of a
+// cell are a contiguous key range.
+type Index struct {
+	// Prefix is the key prefix of every record of the index.
+	Prefix []byte
+	// Encoding is the layout of the location.
+	Encoding Encoding
+	// MaxRanges is the largest number of cells a query scans. Queries use the smallest cells that cover the query
+	// area within it. Defaults to 16.
+	MaxRanges int
+}
+
+// Hit is a record found by a query.
+type Hit struct {
+	Point Point
+	ID    []byte
+	Value []byte
+	// Distance is the distance in meters from the center of a Near query.
+	Distance float64
+}
+
+// Key returns the key of the record id at p.
+func (ix Index) Key(p Point, id []byte) []byte {
+	key := append([]byte{}, ix.Prefix...)
+	if ix.Encoding == EncodingZOrder {
+		key = keys.AppendUint64(key, ZOrder(p))
+	} else {
+		key = append(key, Encode(p, MaxPrecision)...)
+	}
+	return append(key, id...)
+}
+
+// ParseKey returns the location and the ID of a key built by Key. The location is the center of the cell of the
+// key, which is within a few centimeters of the original point.
+func (ix Index) ParseKey(key []byte) (Point, []byte, error) {
+	if !bytes.HasPrefix(key, ix.Prefix) {
+		return Point{}, nil, fmt.Errorf("key %q does not have prefix %q", key, ix.Prefix)
+	}
+	key = key[len(ix.Prefix):]
+
+	if ix.Encoding == EncodingZOrder {
+		z, id, err := keys.DecodeUint64(key)
+		if err != nil {
+			return Point{}, nil, err
+		}
+		return FromZOrder(z).Center(), id, nil
+	}
+	if len(key) < MaxPrecision {
+		return Point{}, nil, keys.ErrShortKey
+	}
+	box, err := Decode(string(key[:MaxPrecision]))
+	if err != nil {
+		return Point{}, nil, err
+	}
+	return box.Center(), key[MaxPrecision:], nil
+}
+
+// Parser returns a WriteStream parser that keys the location, ID and value parsed from each line by fn.
+func (ix Index) Parser(fn func(line string) (p Point, id []byte, value []byte, err error)) func(string) (*badgerutils.KeyValue, error) {
+	return func(line string) (*badgerutils.KeyValue, error) {
+		p, id, value, err := fn(line)
+		if err != nil {
+			return nil, err
+		}
+		if !p.Valid() {
+			return nil, fmt.Errorf("invalid location %v,%v", p.Lat, p.Lon)
+		}
+		return &badgerutils.KeyValue{Key: ix.Key(p, id), Value: value}, nil
+	}
+}
+
+// Cover returns the key ranges of the cells that cover box, in key order.
+func (ix Index) Cover(box Box) []keys.Range {
+	if box.MinLon > box.MaxLon {
+		west, east := box, box
+		west.MaxLon, east.MinLon = 180, -180
+		return mergeRanges(append(ix.Cover(west), ix.Cover(east)...))
+	}
+
+	maxRanges := ix.MaxRanges
+	if maxRanges <= 0 {
+		maxRanges = 16
+	}
+	step, maxDepth := uint(5), uint(5*MaxPrecision)
+	if ix.Encoding == EncodingZOrder {
+		step, maxDepth = 2, 64
+	}
+
+	depth := uint(0)
+	for d := step; d <= maxDepth; d += step {
+		minLon, minLat, maxLon, maxLat := cellBounds(box, d)
+		if (maxLon-minLon+1)*(maxLat-minLat+1) > uint64(maxRanges) {
+			break
+		}
+		depth = d
+	}
+	if depth == 0 {
+		return []keys.Range{keys.PrefixRange(ix.Prefix)}
+	}
+
+	ranges := make([]keys.Range, 0)
+	minLon, minLat, maxLon, maxLat := cellBounds(box, depth)
+	for lon := minLon; lon <= maxLon; lon++ {
+		for lat := minLat; lat <= maxLat; lat++ {
+			ranges = append(ranges, ix.cellRange(interleave(lon, lat, depth), depth))
+		}
+	}
+	return mergeRanges(ranges)
+}
+
+// cellBounds returns the longitude and latitude indexes of the cells of depth bits at the corners of box.
+func cellBounds(box Box, depth uint) (minLon, minLat, maxLon, maxLat uint64) {
+	lonBits, latBits := (depth+1)/2, depth/2
+	return quantize(box.MinLon, -180, 180, lonBits), quantize(box.MinLat, -90, 90, latBits),
+		quantize(box.MaxLon, -180, 180, lonBits), quantize(box.MaxLat, -90, 90, latBits)
+}
+
+// cellRange returns the key range of the Z-order cell code of depth bits.
+func (ix Index) cellRange(code uint64, depth uint) keys.Range {
+	if ix.Encoding != EncodingZOrder {
+		return keys.PrefixRange(append(append([]byte{}, ix.Prefix...), geohash(code, depth)...))
+	}
+
+	shift := 64 - depth
+	r := keys.Range{Start: keys.AppendUint64(append([]byte{}, ix.Prefix...), code<<shift)}
+	if depth < 64 && code+1 < 1<<depth {
+		r.End = keys.AppendUint64(append([]byte{}, ix.Prefix...), (code+1)<<shift)
+	} else if depth == 64 && code < ^uint64(0) {
+		r.End = keys.AppendUint64(append([]byte{}, ix.Prefix...), code+1)
+	} else {
+		r.End = keys.PrefixEnd(ix.Prefix)
+	}
+	return r
+}
+
+// mergeRanges sorts ranges and merges those that touch or overlap.
+func mergeRanges(ranges []keys.Range) []keys.Range {
+	sort.Slice(ranges, func(i, j int) bool { return bytes.Compare(ranges[i].Start, ranges[j].Start) < 0 })
+	merged := make([]keys.Range, 0, len(ranges))
+	for _, r := range ranges {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if last.End == nil {
+				continue
+			}
+			if bytes.Compare(last.End, r.Start) >= 0 {
+				if r.End == nil || bytes.Compare(r.End, last.End) > 0 {
+					last.End = r.End
+				}
+				continue
+			}
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// Within calls fn for each record of db inside box. The covering ranges are scanned in parallel, so records are
+// not in key order, but fn is never called concurrently. The query stops at the first error of fn.
+func (ix Index) Within(db *badger.DB, box Box, fn func(*Hit) error) error {
+	return ix.query(db, ix.Cover(box), func(hit *Hit) bool {
+		return box.Contains(hit.Point)
+	}, fn)
+}
+
+// Near calls fn for each record of db within radius meters of center, with the distance of the record set. Like
+// Within, records are not in key or distance order.
+func (ix Index) Near(db *badger.DB, center Point, radius float64, fn func(*Hit) error) error {
+	return ix.query(db, ix.Cover(RadiusBox(center, radius)), func(hit *Hit) bool {
+		hit.Distance = Distance(center, hit.Point)
+		return hit.Distance <= radius
+	}, fn)
+}
+
+// query scans ranges in parallel and calls fn serially for the records accepted by match.
+func (ix Index) query(db *badger.DB, ranges []keys.Range, match func(*Hit) bool, fn func(*Hit) error) error {
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	for _, r := range ranges {
+		wg.Add(1)
+		go func(r keys.Range) {
+			defer wg.Done()
+			err := keys.ScanRange(db, r, false, func(kv *badgerutils.KeyValue) error {
+				p, id, err := ix.ParseKey(kv.Key)
+				if err != nil {
+					return err
+				}
+				hit := &Hit{Point: p, ID: id, Value: kv.Value}
+				if !match(hit) {
+					return nil
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				if firstErr != nil {
+					return errStop
+				}
+				if err := fn(hit); err != nil {
+					firstErr = err
+					return errStop
+				}
+				return nil
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && err != errStop && firstErr == nil {
+				firstErr = err
+			}
+		}(r)
+	}
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,55 @@
+package badgerutils
+
+import (
+	"context"
+	"time"
+)
+
+// WriteResult summarizes a write once the stream is exhausted or fails.
+type WriteResult struct {
+	// Records is the number of key/values written.
+	Records int
+	// Batches is the number of batches committed or failed.
+	Batches int
+	// Rejected is the number of lines that could not be translated to a key/value.
+	Rejected int
+	// Elapsed is the duration of the whole write.
+	Elapsed time.Duration
+	// Err is the error returned by the write, if any.
+	Err error
+}
+
+// WriteHooks is called at each stage of a write so tracing, auditing or alerting can be attached to it.
+// Batches are committed concurrently, so OnBatchStart and OnBatchCommitted may be called from several goroutines
+// at once. Embed NopHooks to implement only some of the callbacks.
+type WriteHooks interface {
+	// OnRecordParsed is called for each line translated to a key/value.
+	OnRecordParsed(ctx context.Context, kv *KeyValue)
+	// OnRejected is called for a line that could not be translated to a key/value.
+	OnRejected(ctx context.Context, line string, err error)
+	// OnBatchStart is called before batch, numbered from 1, is committed with n key/values. The returned context
+	// is passed to OnBatchCommitted for the same batch, e.g. to carry a tracing span.
+	OnBatchStart(ctx context.Context, batch, n int) context.Context
+	// OnBatchCommitted is called once batch has been committed with n key/values written, or has failed with err.
+	OnBatchCommitted(ctx context.Context, batch, n int, dur time.Duration, err error)
+	// OnComplete is called once when the write returns.
+	OnComplete(ctx context.Context, result WriteResult)
+}
+
+// NopHooks implements WriteHooks with callbacks that do nothing.
+type NopHooks struct{}
+
+// OnRecordParsed does nothing.
+func (NopHooks) OnRecordParsed(context.Context, *KeyValue) {}
+
+// OnRejected does nothing.
+func (NopHooks) OnRejected(context.Context, string, error) {}
+
+// OnBatchStart returns ctx.
+func (NopHooks) OnBatchStart(ctx context.Context, batch, n int) context.Context { return ctx }
+
+// OnBatchCommitted does nothing.
+func (NopHooks) OnBatchCommitted(context.Context, int, int, time.Duration, error) {}
+
+// OnComplete does nothing.
+func (NopHooks) OnComplete(context.Context, WriteResult) {}
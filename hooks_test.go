@@ -0,0 +1,98 @@
+package badgerutils
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type ctxKey struct{}
+
+type recordingHooks struct {
+	NopHooks
+	mu        sync.Mutex
+	parsed    []string
+	rejected  []string
+	started   []int
+	committed []int
+	spans     []interface{}
+	result    *WriteResult
+}
+
+func (h *recordingHooks) OnRecordParsed(ctx context.Context, kv *KeyValue) {
+	h.parsed = append(h.parsed, string(kv.Key))
+}
+
+func (h *recordingHooks) OnRejected(ctx context.Context, line string, err error) {
+	h.rejected = append(h.rejected, line)
+}
+
+func (h *recordingHooks) OnBatchStart(ctx context.Context, batch, n int) context.Context {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.started = append(h.started, batch)
+	return context.WithValue(ctx, ctxKey{}, batch)
+}
+
+func (h *recordingHooks) OnBatchCommitted(ctx context.Context, batch, n int, dur time.Duration, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.committed = append(h.committed, n)
+	h.spans = append(h.spans, ctx.Value(ctxKey{}))
+}
+
+func (h *recordingHooks) OnComplete(ctx context.Context, result WriteResult) {
+	h.result = &result
+}
+
+func TestWriteStreamContext(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	hooks := &recordingHooks{}
+	reader := strings.NewReader("key1:value1\nkey2:value2\nkey3:value3")
+	result, err := WriteStreamContext(context.Background(), reader, path.Join(tmpDir, "db"), 2, csvToKeyValue, hooks)
+	require.Nil(t, err)
+	require.Equal(t, 3, result.Records)
+	require.Equal(t, 2, result.Batches)
+	require.Equal(t, 0, result.Rejected)
+
+	require.Equal(t, []string{"key1", "key2", "key3"}, hooks.parsed)
+	sort.Ints(hooks.started)
+	require.Equal(t, []int{1, 2}, hooks.started)
+	sort.Ints(hooks.committed)
+	require.Equal(t, []int{1, 2}, hooks.committed)
+	require.ElementsMatch(t, []interface{}{1, 2}, hooks.spans)
+	require.NotNil(t, hooks.result)
+	require.Equal(t, 3, hooks.result.Records)
+	require.Nil(t, hooks.result.Err)
+
+	hooks = &recordingHooks{}
+	reader = strings.NewReader("key4:value4\ninvalid")
+	_, err = WriteStreamContext(context.Background(), reader, path.Join(tmpDir, "db"), 2, csvToKeyValue, hooks)
+	require.NotNil(t, err)
+	require.Equal(t, []string{"invalid"}, hooks.rejected)
+	require.Equal(t, 1, hooks.result.Rejected)
+	require.Equal(t, 1, hooks.result.Records)
+	require.Equal(t, err, hooks.result.Err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	hooks = &recordingHooks{}
+	reader = strings.NewReader("key5:value5")
+	_, err = WriteStreamContext(ctx, reader, path.Join(tmpDir, "db"), 2, csvToKeyValue, hooks)
+	require.Equal(t, context.Canceled, err)
+	require.Empty(t, hooks.parsed)
+	require.Equal(t, context.Canceled, hooks.result.Err)
+}
@@ -0,0 +1,147 @@
+package badgerutils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/dgraph-io/badger"
+)
+
+// IngestServer accepts newline-delimited records from many concurrent connections and writes them into a Badger.
+//
+// Each connection's records are grouped into batches of up to batchSize records, or fewer when the client pauses
+// sending. Once a batch is committed the server replies on the connection with
+//
+//	OK <batch> <records>
+//
+// or, if the batch failed to commit,
+//
+//	ERR <batch> <error>
+//
+// where batch counts the batches of the connection from 1. Lines that fail to parse are rejected with
+//
+//	REJECT <line> <error>
+//
+// where line counts the lines of the connection from 1. A line longer than 1MB is answered with
+//
+//	ERR line too long
+//
+// and the connection is closed once the batches read before it are committed.
+type IngestServer struct {
+	db             *badger.DB
+	batchSize      int
+	lineToKeyValue func(string) (*KeyValue, error)
+
+	// pending bounds the number of transactions committing at once across all connections
+	pending chan struct{}
+	commits sync.WaitGroup
+
+	conns *connTracker
+}
+
+// ingestMaxLineLen is the longest line read from a connection.
+const ingestMaxLineLen = 1024 * 1024
+
+// NewIngestServer creates an IngestServer that writes into db. maxPending is the maximum number of batches
+// committing at once and defaults to 16 when less than 1. Connections stop being read while the limit is reached.
+func NewIngestServer(db *badger.DB, batchSize, maxPending int, lineToKeyValue func(string) (*KeyValue, error)) *IngestServer {
+	if maxPending < 1 {
+		maxPending = 16
+	}
+	return &IngestServer{
+		db:             db,
+		batchSize:      batchSize,
+		lineToKeyValue: lineToKeyValue,
+		pending:        make(chan struct{}, maxPending),
+		conns:          newConnTracker(),
+	}
+}
+
+// Serve accepts connections on l until Close is called.
+func (s *IngestServer) Serve(l net.Listener) error {
+	return s.conns.serve(l, s.handle)
+}
+
+// Close stops accepting connections, closes open connections and waits for pending batches to be committed.
+func (s *IngestServer) Close() error {
+	s.conns.close()
+	s.commits.Wait()
+	return nil
+}
+
+// submit commits kvs once a pending slot is free and calls done when the commit completes.
+func (s *IngestServer) submit(kvs []KeyValue, done func(int32, error)) {
+	s.pending <- struct{}{}
+	s.commits.Add(1)
+	go commitBatch(s.db, kvs, ConflictOverwrite, func(written int32, err error) {
+		<-s.pending
+		done(written, err)
+		s.commits.Done()
+	})
+}
+
+func (s *IngestServer) handle(conn net.Conn) {
+	// Replies are written from commit callbacks so writes to the connection are serialized
+	var replyMu sync.Mutex
+	reply := func(format string, a ...interface{}) {
+		replyMu.Lock()
+		defer replyMu.Unlock()
+		fmt.Fprintf(conn, format, a...)
+	}
+
+	var acks sync.WaitGroup
+	batchNum := 0
+	kvBatch := make([]KeyValue, 0)
+	flush := func() {
+		if len(kvBatch) == 0 {
+			return
+		}
+		batchNum++
+		batch := batchNum
+		acks.Add(1)
+		s.submit(kvBatch, func(written int32, err error) {
+			if err != nil {
+				reply("ERR %v %v\n", batch, err)
+			} else {
+				reply("OK %v %v\n", batch, written)
+			}
+			acks.Done()
+		})
+		kvBatch = make([]KeyValue, 0)
+	}
+
+	reader := bufio.NewReader(conn)
+	lineNum := 0
+	for {
+		line, err := readLine(reader, ingestMaxLineLen)
+		if err == errLineTooLong {
+			reply("ERR %v\n", err)
+			break
+		}
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			if err != io.EOF && !s.conns.isClosed() {
+				DefaultLogger.Log(LevelWarn, "Reading connection failed", "remote", conn.RemoteAddr(), "error", err)
+			}
+			break
+		}
+
+		lineNum++
+		kv, err := s.lineToKeyValue(string(line))
+		if err != nil {
+			DefaultMetrics.IncCounter(MetricRecordsRejected, 1)
+			reply("REJECT %v %v\n", lineNum, err)
+		} else {
+			kvBatch = append(kvBatch, *kv)
+		}
+
+		// Commit full batches, or partial batches once the client has nothing more buffered
+		if len(kvBatch) >= s.batchSize || reader.Buffered() == 0 {
+			flush()
+		}
+	}
+	flush()
+	acks.Wait()
+}
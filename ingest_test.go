@@ -0,0 +1,171 @@
+package badgerutils
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngestServer(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(path.Join(tmpDir, "db"))
+	require.Nil(t, err)
+	defer db.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+
+	server := NewIngestServer(db, 2, 4, csvToKeyValue)
+	go server.Serve(l)
+
+	var wg sync.WaitGroup
+	replies := make([][]string, 3)
+	dialErrs := make(chan error, len(replies))
+	for c := range replies {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			conn, err := net.Dial("tcp", l.Addr().String())
+			if err != nil {
+				dialErrs <- err
+				return
+			}
+			defer conn.Close()
+
+			fmt.Fprintf(conn, "client%v/key1:value1\nclient%v/key2:value2\ninvalid\nclient%v/key3:value3\n", c, c, c)
+			conn.(*net.TCPConn).CloseWrite()
+
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				replies[c] = append(replies[c], scanner.Text())
+			}
+		}(c)
+	}
+	wg.Wait()
+	close(dialErrs)
+	for err := range dialErrs {
+		require.Nil(t, err)
+	}
+	require.Nil(t, server.Close())
+
+	for _, r := range replies {
+		written := 0
+		rejected := 0
+		for _, reply := range r {
+			switch {
+			case strings.HasPrefix(reply, "OK "):
+				var batch, n int
+				_, err := fmt.Sscanf(reply, "OK %d %d", &batch, &n)
+				require.Nil(t, err)
+				written += n
+			case strings.HasPrefix(reply, "REJECT 3 "):
+				rejected++
+			default:
+				t.Fatalf("unexpected reply %q", reply)
+			}
+		}
+		require.Equal(t, 3, written)
+		require.Equal(t, 1, rejected)
+	}
+
+	keys := make([]string, 0)
+	_, err = Scan(db, ScanOptions{KeysOnly: true}, func(kv *KeyValue) error {
+		keys = append(keys, string(kv.Key))
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, 9, len(keys))
+	require.True(t, sort.StringsAreSorted(keys))
+}
+
+func TestIngestServerDefaultPending(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(path.Join(tmpDir, "db"))
+	require.Nil(t, err)
+	defer db.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+
+	// A maxPending below 1 is defaulted instead of blocking every batch
+	server := NewIngestServer(db, 1, 0, csvToKeyValue)
+	go server.Serve(l)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.Nil(t, err)
+	defer conn.Close()
+	require.Nil(t, conn.SetReadDeadline(time.Now().Add(10*time.Second)))
+
+	fmt.Fprintf(conn, "key1:value1\n")
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	require.Nil(t, err)
+	require.Equal(t, "OK 1 1\n", reply)
+}
+
+func TestIngestServerLongLine(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(path.Join(tmpDir, "db"))
+	require.Nil(t, err)
+	defer db.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+
+	server := NewIngestServer(db, 10, 1, csvToKeyValue)
+	go server.Serve(l)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.Nil(t, err)
+	defer conn.Close()
+	require.Nil(t, conn.SetDeadline(time.Now().Add(10*time.Second)))
+
+	// The long line is one byte over the limit, so the server reads all of it before closing the connection
+	go func() {
+		fmt.Fprintf(conn, "key1:value1\nkey2:%v", strings.Repeat("v", ingestMaxLineLen-4))
+		conn.(*net.TCPConn).CloseWrite()
+	}()
+
+	// The records read before the long line are committed before the connection is closed
+	replies := make([]string, 0)
+	r := bufio.NewReader(conn)
+	for {
+		reply, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		replies = append(replies, reply)
+	}
+	sort.Strings(replies)
+	require.Equal(t, []string{"ERR line too long\n", "OK 1 1\n"}, replies)
+
+	value, err := Get(db, []byte("key1"))
+	require.Nil(t, err)
+	require.Equal(t, "value1", string(value))
+}
@@ -0,0 +1,182 @@
+// Package keys encodes values into Badger keys that sort bytewise in the same order as the values, and computes
+// the key ranges to scan for prefixes of those keys.
+package keys
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrShortKey is returned when a key ends before the value being decoded.
+var ErrShortKey = errors.New("key too short")
+
+// AppendUint64 appends v as 8 big-endian bytes.
+func AppendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// DecodeUint64 decodes a value encoded by AppendUint64 and returns the rest of b.
+func DecodeUint64(b []byte) (uint64, []byte, error) {
+	if len(b) < 8 {
+		return 0, b, ErrShortKey
+	}
+	return binary.BigEndian.Uint64(b), b[8:], nil
+}
+
+// AppendInt64 appends v as 8 big-endian bytes with the sign bit flipped so negative values sort first.
+func AppendInt64(b []byte, v int64) []byte {
+	return AppendUint64(b, uint64(v)^(1<<63))
+}
+
+// DecodeInt64 decodes a value encoded by AppendInt64 and returns the rest of b.
+func DecodeInt64(b []byte) (int64, []byte, error) {
+	u, rest, err := DecodeUint64(b)
+	return int64(u ^ (1 << 63)), rest, err
+}
+
+// AppendFloat64 appends v as 8 big-endian bytes that sort in numeric order. Negative values have every bit flipped
+// and positive values only the sign bit.
+func AppendFloat64(b []byte, v float64) []byte {
+	u := math.Float64bits(v)
+	if u&(1<<63) != 0 {
+		u = ^u
+	} else {
+		u |= 1 << 63
+	}
+	return AppendUint64(b, u)
+}
+
+// DecodeFloat64 decodes a value encoded by AppendFloat64 and returns the rest of b.
+func DecodeFloat64(b []byte) (float64, []byte, error) {
+	u, rest, err := DecodeUint64(b)
+	if u&(1<<63) != 0 {
+		u &^= 1 << 63
+	} else {
+		u = ^u
+	}
+	return math.Float64frombits(u), rest, err
+}
+
+// MinTime and MaxTime are the first and last times AppendTime encodes, those whose nanoseconds since the Unix epoch
+// fit an int64, from 1677-09-21 to 2262-04-11.
+var (
+	MinTime = time.Unix(0, math.MinInt64).UTC()
+	MaxTime = time.Unix(0, math.MaxInt64).UTC()
+)
+
+// ValidTime reports whether t is within MinTime and MaxTime, so that AppendTime encodes it exactly.
+func ValidTime(t time.Time) bool {
+	return !t.Before(MinTime) && !t.After(MaxTime)
+}
+
+// AppendTime appends t as its nanoseconds since the Unix epoch with AppendInt64. The location of t is not kept.
+// Times outside MinTime and MaxTime, such as the zero time.Time, are encoded as the closest of the two, which keeps
+// them in order as range bounds but does not decode them to the same instant. Check keys with ValidTime.
+func AppendTime(b []byte, t time.Time) []byte {
+	switch {
+	case t.Before(MinTime):
+		t = MinTime
+	case t.After(MaxTime):
+		t = MaxTime
+	}
+	return AppendInt64(b, t.UnixNano())
+}
+
+// DecodeTime decodes a value encoded by AppendTime in UTC and returns the rest of b.
+func DecodeTime(b []byte) (time.Time, []byte, error) {
+	ns, rest, err := DecodeInt64(b)
+	return time.Unix(0, ns).UTC(), rest, err
+}
+
+// AppendBytes appends v with each 0x00 byte escaped as 0x00 0xFF and terminated by 0x00 0x01, so that shorter
+// values sort before longer values they are a prefix of and the end of v is found when decoding.
+func AppendBytes(b []byte, v []byte) []byte {
+	for {
+		i := bytes.IndexByte(v, 0x00)
+		if i < 0 {
+			break
+		}
+		b = append(append(b, v[:i]...), 0x00, 0xFF)
+		v = v[i+1:]
+	}
+	return append(append(b, v...), 0x00, 0x01)
+}
+
+// DecodeBytes decodes a value encoded by AppendBytes and returns the rest of b.
+func DecodeBytes(b []byte) ([]byte, []byte, error) {
+	v := make([]byte, 0)
+	for {
+		i := bytes.IndexByte(b, 0x00)
+		if i < 0 || i+1 == len(b) {
+			return nil, b, ErrShortKey
+		}
+		v = append(v, b[:i]...)
+		switch b[i+1] {
+		case 0x01:
+			return v, b[i+2:], nil
+		case 0xFF:
+			v = append(v, 0x00)
+			b = b[i+2:]
+		default:
+			return nil, b, fmt.Errorf("invalid escape 0x00 0x%02x", b[i+1])
+		}
+	}
+}
+
+// AppendString appends v with AppendBytes.
+func AppendString(b []byte, v string) []byte {
+	return AppendBytes(b, []byte(v))
+}
+
+// DecodeString decodes a value encoded by AppendString and returns the rest of b.
+func DecodeString(b []byte) (string, []byte, error) {
+	v, rest, err := DecodeBytes(b)
+	return string(v), rest, err
+}
+
+// PrefixEnd returns the first key after every key with prefix, or nil when there is none because prefix is empty
+// or only 0xFF bytes.
+func PrefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xFF {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// Range is the keys from Start up to but excluding End. A nil End has no upper bound.
+type Range struct {
+	Start []byte
+	End   []byte
+}
+
+// PrefixRange returns the range of the keys with prefix.
+func PrefixRange(prefix []byte) Range {
+	return Range{Start: append([]byte{}, prefix...), End: PrefixEnd(prefix)}
+}
+
+// Contains reports whether key is in the range.
+func (r Range) Contains(key []byte) bool {
+	return bytes.Compare(key, r.Start) >= 0 && (r.End == nil || bytes.Compare(key, r.End) < 0)
+}
+
+// Prefix returns the longest prefix shared by every key of the range, which is the narrowest prefix to scan.
+func (r Range) Prefix() []byte {
+	if r.End == nil {
+		return nil
+	}
+	n := 0
+	for n < len(r.Start) && n < len(r.End) && r.Start[n] == r.End[n] {
+		n++
+	}
+	return r.Start[:n]
+}
@@ -0,0 +1,160 @@
+package keys
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Surfline/badgerutils"
+	"github.com/Surfline/badgerutils/badgerutilstest"
+	"github.com/stretchr/testify/require"
+)
+
+// requireSorted checks that the keys encoded from values in ascending order are also in ascending order.
+func requireSorted(t *testing.T, encoded [][]byte) {
+	require.True(t, sort.SliceIsSorted(encoded, func(i, j int) bool {
+		return bytes.Compare(encoded[i], encoded[j]) < 0
+	}))
+}
+
+func TestIntegers(t *testing.T) {
+	ints := []int64{math.MinInt64, -1000, -1, 0, 1, 255, 256, math.MaxInt64}
+	encoded := make([][]byte, 0)
+	for _, v := range ints {
+		b := AppendInt64(nil, v)
+		decoded, rest, err := DecodeInt64(b)
+		require.Nil(t, err)
+		require.Equal(t, v, decoded)
+		require.Empty(t, rest)
+		encoded = append(encoded, b)
+	}
+	requireSorted(t, encoded)
+
+	_, _, err := DecodeUint64([]byte{1, 2})
+	require.Equal(t, ErrShortKey, err)
+}
+
+func TestFloats(t *testing.T) {
+	floats := []float64{math.Inf(-1), -1e10, -1.5, -0.25, 0, 0.25, 1.5, 1e10, math.Inf(1)}
+	encoded := make([][]byte, 0)
+	for _, v := range floats {
+		b := AppendFloat64(nil, v)
+		decoded, _, err := DecodeFloat64(b)
+		require.Nil(t, err)
+		require.Equal(t, v, decoded)
+		encoded = append(encoded, b)
+	}
+	requireSorted(t, encoded)
+}
+
+func TestStrings(t *testing.T) {
+	strings := []string{"", "\x00", "\x00\x00", "\x00a", "a", "a\x00", "a\x00b", "ab", "b", "\xff"}
+	encoded := make([][]byte, 0)
+	for _, v := range strings {
+		b := AppendString(nil, v)
+		decoded, rest, err := DecodeString(append(b, 'x'))
+		require.Nil(t, err)
+		require.Equal(t, v, decoded)
+		require.Equal(t, []byte("x"), rest)
+		encoded = append(encoded, b)
+	}
+	requireSorted(t, encoded)
+
+	_, _, err := DecodeString([]byte("abc"))
+	require.Equal(t, ErrShortKey, err)
+	_, _, err = DecodeString([]byte{'a', 0x00, 0x02})
+	require.NotNil(t, err)
+}
+
+func TestTimes(t *testing.T) {
+	for _, at := range []time.Time{MinTime, time.Unix(0, 0).UTC(), time.Date(2026, 10, 18, 6, 0, 0, 1, time.UTC), MaxTime} {
+		require.True(t, ValidTime(at))
+		decoded, rest, err := DecodeTime(AppendTime(nil, at))
+		require.Nil(t, err)
+		require.Empty(t, rest)
+		require.True(t, at.Equal(decoded), "%v decoded as %v", at, decoded)
+	}
+
+	// Times outside the range are not valid keys, but keep their order as range bounds
+	require.False(t, ValidTime(time.Time{}))
+	require.False(t, ValidTime(MaxTime.Add(time.Nanosecond)))
+	requireSorted(t, [][]byte{
+		AppendTime(nil, time.Time{}),
+		AppendTime(nil, time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)),
+		AppendTime(nil, time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC)),
+	})
+	require.Equal(t, AppendTime(nil, MinTime), AppendTime(nil, time.Time{}))
+}
+
+func TestTuple(t *testing.T) {
+	at := time.Date(2026, 10, 18, 6, 0, 0, 0, time.UTC)
+	key, err := Pack("spot", 42, uint64(7), -1.5, at, []byte{0x00, 0x01}, true)
+	require.Nil(t, err)
+	tuple, err := Unpack(key)
+	require.Nil(t, err)
+	require.Equal(t, Tuple{"spot", int64(42), uint64(7), -1.5, at, []byte{0x00, 0x01}, true}, tuple)
+
+	_, err = Pack(struct{}{})
+	require.NotNil(t, err)
+	_, err = Unpack([]byte{0xEE})
+	require.NotNil(t, err)
+
+	// Tuples sort element by element, unlike concatenated strings
+	requireSorted(t, [][]byte{
+		MustPack("a", 2),
+		MustPack("a", 10),
+		MustPack("a", 10, "x"),
+		MustPack("ab", -5),
+		MustPack("b", at),
+		MustPack("b", at.Add(time.Second)),
+	})
+	require.True(t, bytes.HasPrefix(MustPack("a", 10, "x"), MustPack("a", 10)))
+}
+
+func TestRanges(t *testing.T) {
+	require.Equal(t, []byte("ac"), PrefixEnd([]byte("ab")))
+	require.Equal(t, []byte("b"), PrefixEnd([]byte{'a', 0xFF, 0xFF}))
+	require.Nil(t, PrefixEnd([]byte{0xFF}))
+	require.Nil(t, PrefixEnd(nil))
+
+	r := PrefixRange([]byte("ab"))
+	require.True(t, r.Contains([]byte("ab")))
+	require.True(t, r.Contains([]byte("ab\xff")))
+	require.False(t, r.Contains([]byte("ac")))
+	require.False(t, r.Contains([]byte("aa")))
+	require.Equal(t, []byte("a"), r.Prefix())
+
+	db := badgerutilstest.TempDB(t)
+	at := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	kvs := make([]badgerutils.KeyValue, 0)
+	for _, id := range []string{"a", "b"} {
+		for hour := 0; hour < 24; hour++ {
+			kvs = append(kvs, badgerutils.KeyValue{Key: MustPack(id, at.Add(time.Duration(hour)*time.Hour))})
+		}
+	}
+	badgerutilstest.Seed(t, db, kvs)
+
+	r, err := TupleRange(Tuple{"a"}, at.Add(6*time.Hour), at.Add(9*time.Hour))
+	require.Nil(t, err)
+	hours := make([]int, 0)
+	require.Nil(t, ScanRange(db, r, true, func(kv *badgerutils.KeyValue) error {
+		tuple, err := Unpack(kv.Key)
+		require.Nil(t, err)
+		require.Equal(t, "a", tuple[0])
+		hours = append(hours, tuple[1].(time.Time).Hour())
+		return nil
+	}))
+	require.Equal(t, []int{6, 7, 8}, hours)
+
+	opts, err := TupleScanOptions("b")
+	require.Nil(t, err)
+	count := 0
+	_, err = badgerutils.Scan(db, opts, func(*badgerutils.KeyValue) error {
+		count++
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, 24, count)
+}
@@ -0,0 +1,19 @@
+package keys
+
+import (
+	"github.com/Surfline/badgerutils"
+	"github.com/dgraph-io/badger"
+)
+
+// TupleScanOptions returns the badgerutils.ScanOptions of the keys packed from tuples that start with prefix.
+func TupleScanOptions(prefix ...interface{}) (badgerutils.ScanOptions, error) {
+	key, err := Pack(prefix...)
+	return badgerutils.ScanOptions{Prefix: key}, err
+}
+
+// ScanRange calls fn for each key/value of db in r in key order. Only keys are read when keysOnly is set.
+func ScanRange(db *badger.DB, r Range, keysOnly bool, fn func(*badgerutils.KeyValue) error) error {
+	opts := badgerutils.ScanOptions{Prefix: r.Prefix(), Start: r.Start, End: r.End, KeysOnly: keysOnly}
+	_, err := badgerutils.Scan(db, opts, fn)
+	return err
+}
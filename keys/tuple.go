@@ -0,0 +1,125 @@
+package keys
+
+import (
+	"fmt"
+	"time"
+)
+
+// Type codes of tuple elements. Elements of different types sort by their type code.
+const (
+	codeFalse  byte = 0x02
+	codeTrue   byte = 0x03
+	codeInt    byte = 0x10
+	codeUint   byte = 0x11
+	codeFloat  byte = 0x12
+	codeTime   byte = 0x13
+	codeBytes  byte = 0x20
+	codeString byte = 0x21
+)
+
+// Tuple is a list of values encoded into a key with Pack. Elements are bool, signed and unsigned integers,
+// float64, time.Time, []byte or string.
+type Tuple []interface{}
+
+// Pack encodes elems into a key that sorts element by element. A packed tuple is a prefix of every tuple it is
+// the start of, so Pack(id) is the prefix of the keys Pack(id, t) for every t.
+func Pack(elems ...interface{}) ([]byte, error) {
+	return AppendTuple(nil, elems...)
+}
+
+// MustPack is Pack for elements whose types are known to be supported. It panics otherwise.
+func MustPack(elems ...interface{}) []byte {
+	b, err := Pack(elems...)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// AppendTuple appends the encoding of elems to b.
+func AppendTuple(b []byte, elems ...interface{}) ([]byte, error) {
+	for _, elem := range elems {
+		switch v := elem.(type) {
+		case bool:
+			if v {
+				b = append(b, codeTrue)
+			} else {
+				b = append(b, codeFalse)
+			}
+		case int:
+			b = AppendInt64(append(b, codeInt), int64(v))
+		case int32:
+			b = AppendInt64(append(b, codeInt), int64(v))
+		case int64:
+			b = AppendInt64(append(b, codeInt), v)
+		case uint:
+			b = AppendUint64(append(b, codeUint), uint64(v))
+		case uint32:
+			b = AppendUint64(append(b, codeUint), uint64(v))
+		case uint64:
+			b = AppendUint64(append(b, codeUint), v)
+		case float64:
+			b = AppendFloat64(append(b, codeFloat), v)
+		case time.Time:
+			b = AppendTime(append(b, codeTime), v)
+		case []byte:
+			b = AppendBytes(append(b, codeBytes), v)
+		case string:
+			b = AppendString(append(b, codeString), v)
+		default:
+			return nil, fmt.Errorf("unsupported tuple element type %T", elem)
+		}
+	}
+	return b, nil
+}
+
+// Unpack decodes a key encoded by Pack. Integers are decoded as int64 or uint64.
+func Unpack(b []byte) (Tuple, error) {
+	t := make(Tuple, 0)
+	for len(b) > 0 {
+		code := b[0]
+		b = b[1:]
+
+		var elem interface{}
+		var err error
+		switch code {
+		case codeFalse:
+			elem = false
+		case codeTrue:
+			elem = true
+		case codeInt:
+			elem, b, err = DecodeInt64(b)
+		case codeUint:
+			elem, b, err = DecodeUint64(b)
+		case codeFloat:
+			elem, b, err = DecodeFloat64(b)
+		case codeTime:
+			elem, b, err = DecodeTime(b)
+		case codeBytes:
+			elem, b, err = DecodeBytes(b)
+		case codeString:
+			elem, b, err = DecodeString(b)
+		default:
+			err = fmt.Errorf("unknown type code 0x%02x", code)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("element %v: %v", len(t), err)
+		}
+		t = append(t, elem)
+	}
+	return t, nil
+}
+
+// TupleRange returns the range of the keys packed from tuples that start with prefix and continue with an element
+// from start up to but excluding end, e.g. TupleRange(Tuple{id}, from, to) for the times of a series.
+func TupleRange(prefix Tuple, start, end interface{}) (Range, error) {
+	startKey, err := Pack(append(append(Tuple{}, prefix...), start)...)
+	if err != nil {
+		return Range{}, err
+	}
+	endKey, err := Pack(append(append(Tuple{}, prefix...), end)...)
+	if err != nil {
+		return Range{}, err
+	}
+	return Range{Start: startKey, End: endKey}, nil
+}
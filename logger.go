@@ -0,0 +1,122 @@
+package badgerutils
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Level is the severity of a log entry.
+type Level int
+
+// Log levels, from the most to the least verbose.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel returns the Level named debug, info, warn or error.
+func ParseLevel(name string) (Level, error) {
+	for i, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return Level(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %v", name)
+}
+
+// Logger receives leveled log entries. keyvals alternates keys and values, e.g. "records", 10, "elapsed", d.
+type Logger interface {
+	Log(level Level, msg string, keyvals ...interface{})
+}
+
+// DefaultLogger receives the log entries of every function in the package. It discards them by default.
+var DefaultLogger Logger = NopLogger{}
+
+// NopLogger discards all log entries.
+type NopLogger struct{}
+
+// Log does nothing.
+func (NopLogger) Log(Level, string, ...interface{}) {}
+
+// LoggerFunc adapts a function to a Logger, e.g. to forward entries to a structured logger:
+//
+//	badgerutils.DefaultLogger = badgerutils.LoggerFunc(func(level badgerutils.Level, msg string, keyvals ...interface{}) {
+//		sugar.Infow(msg, keyvals...)
+//	})
+type LoggerFunc func(level Level, msg string, keyvals ...interface{})
+
+// Log calls f.
+func (f LoggerFunc) Log(level Level, msg string, keyvals ...interface{}) {
+	f(level, msg, keyvals...)
+}
+
+type stdLogger struct {
+	logger *log.Logger
+	min    Level
+}
+
+// NewStdLogger returns a Logger that writes entries at min level or above to logger as logfmt lines:
+//
+//	level=info msg="Inserted records" records=10 elapsed=474.69µs
+func NewStdLogger(logger *log.Logger, min Level) Logger {
+	return &stdLogger{logger: logger, min: min}
+}
+
+func logfmtValue(v interface{}) string {
+	s := fmt.Sprintf("%v", v)
+	if s == "" || strings.ContainsAny(s, " =\"\t\n") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
+
+func (l *stdLogger) Log(level Level, msg string, keyvals ...interface{}) {
+	if level < l.min {
+		return
+	}
+
+	var line bytes.Buffer
+	fmt.Fprintf(&line, "level=%v msg=%v", level, logfmtValue(msg))
+	for i := 0; i < len(keyvals); i += 2 {
+		var value interface{} = "(missing)"
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		fmt.Fprintf(&line, " %v=%v", keyvals[i], logfmtValue(value))
+	}
+	l.logger.Output(2, line.String())
+}
+
+// logWriter forwards lines written to the standard library log package to a Logger.
+type logWriter struct {
+	logger Logger
+	level  Level
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	w.logger.Log(w.level, strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
+// RouteStdLog sends everything written with the standard library log package to logger at level. The vendored
+// Badger version has no Logger option and logs its warnings through the standard library log package, so this
+// routes them to the same logger as the rest of the package. It changes the output of the global log package
+// for the whole process, so logger must not itself write to the global log package.
+func RouteStdLog(logger Logger, level Level) {
+	log.SetFlags(0)
+	log.SetPrefix("")
+	log.SetOutput(logWriter{logger: logger, level: level})
+}
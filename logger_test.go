@@ -0,0 +1,48 @@
+package badgerutils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewStdLogger(log.New(&buf, "", 0), LevelInfo)
+
+	logger.Log(LevelDebug, "Hidden")
+	logger.Log(LevelInfo, "Inserted records", "records", 10, "path", "a b", "empty", "")
+	logger.Log(LevelError, "Failed", "error")
+	require.Equal(t, `level=info msg="Inserted records" records=10 path="a b" empty=""
+level=error msg=Failed error=(missing)
+`, buf.String())
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("WARN")
+	require.Nil(t, err)
+	require.Equal(t, LevelWarn, level)
+	require.Equal(t, "warn", level.String())
+
+	_, err = ParseLevel("verbose")
+	require.NotNil(t, err)
+}
+
+func TestRouteStdLog(t *testing.T) {
+	defer func(w io.Writer, prefix string, flags int) {
+		log.SetOutput(w)
+		log.SetPrefix(prefix)
+		log.SetFlags(flags)
+	}(log.Writer(), log.Prefix(), log.Flags())
+
+	var entries []string
+	RouteStdLog(LoggerFunc(func(level Level, msg string, keyvals ...interface{}) {
+		entries = append(entries, fmt.Sprintf("%v %v", level, msg))
+	}), LevelWarn)
+	log.Printf("Error while running merge operation: %v", "failed")
+	require.Equal(t, []string{"warn Error while running merge operation: failed"}, entries)
+}
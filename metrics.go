@@ -0,0 +1,205 @@
+package badgerutils
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/dgraph-io/badger"
+)
+
+// Metric names reported to Metrics.
+const (
+	MetricRecordsWritten    = "badgerutils_records_written_total"
+	MetricBytesWritten      = "badgerutils_bytes_written_total"
+	MetricRecordsRejected   = "badgerutils_records_rejected_total"
+	MetricBatchCommits      = "badgerutils_batch_commits_total"
+	MetricBatchCommitErrors = "badgerutils_batch_commit_errors_total"
+	MetricBatchCommitTime   = "badgerutils_batch_commit_seconds"
+	MetricBatchSize         = "badgerutils_batch_size_records"
+	MetricTxnsInFlight      = "badgerutils_transactions_in_flight"
+	MetricLSMSize           = "badgerutils_lsm_size_bytes"
+	MetricVlogSize          = "badgerutils_vlog_size_bytes"
+	MetricReads             = "badgerutils_reads_total"
+	MetricReadErrors        = "badgerutils_read_errors_total"
+	MetricRecordsRead       = "badgerutils_records_read_total"
+	MetricReadTime          = "badgerutils_read_seconds"
+)
+
+// Metrics receives measurements from the writer and read helpers. Implement it to send metrics to a backend
+// other than Prometheus.
+type Metrics interface {
+	// IncCounter adds delta to a counter.
+	IncCounter(name string, delta float64)
+	// AddGauge adds delta to a gauge.
+	AddGauge(name string, delta float64)
+	// SetGauge sets a gauge.
+	SetGauge(name string, value float64)
+	// Observe records a value in a histogram.
+	Observe(name string, value float64)
+}
+
+// DefaultMetrics receives the measurements of every function in the package. It discards them by default.
+var DefaultMetrics Metrics = nopMetrics{}
+
+type nopMetrics struct{}
+
+func (nopMetrics) IncCounter(string, float64) {}
+func (nopMetrics) AddGauge(string, float64)   {}
+func (nopMetrics) SetGauge(string, float64)   {}
+func (nopMetrics) Observe(string, float64)    {}
+
+// recordSize reports the LSM and value log sizes of db.
+func recordSize(db *badger.DB) {
+	lsm, vlog := db.Size()
+	DefaultMetrics.SetGauge(MetricLSMSize, float64(lsm))
+	DefaultMetrics.SetGauge(MetricVlogSize, float64(vlog))
+}
+
+var defaultBuckets = map[string][]float64{
+	MetricBatchCommitTime: {.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	MetricBatchSize:       {1, 10, 100, 1000, 10000, 100000},
+	MetricReadTime:        {.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+}
+
+var metricHelp = map[string]string{
+	MetricRecordsWritten:    "Records written.",
+	MetricBytesWritten:      "Key and value bytes written.",
+	MetricRecordsRejected:   "Records that could not be parsed.",
+	MetricBatchCommits:      "Batches committed.",
+	MetricBatchCommitErrors: "Batches that failed to commit.",
+	MetricBatchCommitTime:   "Batch commit latency in seconds.",
+	MetricBatchSize:         "Records per batch.",
+	MetricTxnsInFlight:      "Write transactions being committed.",
+	MetricLSMSize:           "Size of the LSM tree in bytes.",
+	MetricVlogSize:          "Size of the value log in bytes.",
+	MetricReads:             "Read helper calls.",
+	MetricReadErrors:        "Read helper calls that failed.",
+	MetricRecordsRead:       "Records returned by read helpers.",
+	MetricReadTime:          "Read helper latency in seconds.",
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+// PrometheusMetrics collects metrics in memory and serves them in the Prometheus text format.
+type PrometheusMetrics struct {
+	mu         sync.Mutex
+	counters   map[string]float64
+	gauges     map[string]float64
+	histograms map[string]*histogram
+}
+
+// NewPrometheusMetrics creates an empty PrometheusMetrics.
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		counters:   make(map[string]float64),
+		gauges:     make(map[string]float64),
+		histograms: make(map[string]*histogram),
+	}
+}
+
+// IncCounter adds delta to a counter.
+func (m *PrometheusMetrics) IncCounter(name string, delta float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters[name] += delta
+}
+
+// AddGauge adds delta to a gauge.
+func (m *PrometheusMetrics) AddGauge(name string, delta float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gauges[name] += delta
+}
+
+// SetGauge sets a gauge.
+func (m *PrometheusMetrics) SetGauge(name string, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gauges[name] = value
+}
+
+// Observe records a value in a histogram.
+func (m *PrometheusMetrics) Observe(name string, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.histograms[name]
+	if !ok {
+		buckets, ok := defaultBuckets[name]
+		if !ok {
+			buckets = defaultBuckets[MetricBatchCommitTime]
+		}
+		h = &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+		m.histograms[name] = h
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeHeader(w http.ResponseWriter, name, kind string) {
+	if help, ok := metricHelp[name]; ok {
+		fmt.Fprintf(w, "# HELP %v %v\n", name, help)
+	}
+	fmt.Fprintf(w, "# TYPE %v %v\n", name, kind)
+}
+
+// ServeHTTP writes all metrics in the Prometheus text format.
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, name := range sortedKeys(m.counters) {
+		writeHeader(w, name, "counter")
+		fmt.Fprintf(w, "%v %v\n", name, formatFloat(m.counters[name]))
+	}
+	for _, name := range sortedKeys(m.gauges) {
+		writeHeader(w, name, "gauge")
+		fmt.Fprintf(w, "%v %v\n", name, formatFloat(m.gauges[name]))
+	}
+
+	names := make([]string, 0, len(m.histograms))
+	for name := range m.histograms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		h := m.histograms[name]
+		writeHeader(w, name, "histogram")
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%v_bucket{le=\"%v\"} %v\n", name, formatFloat(bound), h.counts[i])
+		}
+		fmt.Fprintf(w, "%v_bucket{le=\"+Inf\"} %v\n", name, h.count)
+		fmt.Fprintf(w, "%v_sum %v\n", name, formatFloat(h.sum))
+		fmt.Fprintf(w, "%v_count %v\n", name, h.count)
+	}
+}
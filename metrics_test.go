@@ -0,0 +1,59 @@
+package badgerutils
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	metrics := NewPrometheusMetrics()
+	DefaultMetrics = metrics
+	defer func() { DefaultMetrics = nopMetrics{} }()
+
+	dbPath := path.Join(tmpDir, "db")
+	reader := strings.NewReader(`key1:value1
+key2:value2
+key3:value3`)
+	require.Nil(t, WriteStream(reader, dbPath, 2, csvToKeyValue))
+
+	db, err := Open(dbPath)
+	require.Nil(t, err)
+	defer db.Close()
+
+	_, err = Get(db, []byte("key1"))
+	require.Nil(t, err)
+	_, err = Scan(db, ScanOptions{Prefix: []byte("key")}, func(*KeyValue) error { return nil })
+	require.Nil(t, err)
+
+	rec := httptest.NewRecorder()
+	NewHandler(db, HandlerOptions{Metrics: metrics}).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, 200, rec.Code)
+	body := rec.Body.String()
+
+	require.Contains(t, body, "# TYPE badgerutils_records_written_total counter\nbadgerutils_records_written_total 3\n")
+	require.Contains(t, body, "badgerutils_bytes_written_total 30\n")
+	require.Contains(t, body, "badgerutils_batch_commits_total 2\n")
+	require.Contains(t, body, "badgerutils_transactions_in_flight 0\n")
+	require.Contains(t, body, "badgerutils_batch_size_records_bucket{le=\"1\"} 1\n")
+	require.Contains(t, body, "badgerutils_batch_size_records_count 2\n")
+	require.Contains(t, body, "badgerutils_reads_total 2\n")
+	require.Contains(t, body, "badgerutils_records_read_total 4\n")
+	require.Contains(t, body, "# TYPE badgerutils_read_seconds histogram\n")
+	require.NotContains(t, body, "badgerutils_batch_commit_errors_total")
+
+	err = WriteStream(strings.NewReader("invalid"), path.Join(tmpDir, "db2"), 2, csvToKeyValue)
+	require.NotNil(t, err)
+	require.Equal(t, float64(1), metrics.counters[MetricRecordsRejected])
+}
@@ -0,0 +1,447 @@
+package badgerutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger"
+)
+
+// Granularity is the length of the time buckets of a PartitionedDB.
+type Granularity int
+
+// Partition granularities.
+const (
+	Hourly Granularity = iota
+	Daily
+	Monthly
+)
+
+var granularityLayouts = []string{"2006-01-02T15", "2006-01-02", "2006-01"}
+
+// Start returns the start of the bucket containing t, in UTC.
+func (g Granularity) Start(t time.Time) time.Time {
+	t = t.UTC()
+	switch g {
+	case Hourly:
+		return t.Truncate(time.Hour)
+	case Daily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// End returns the end of the bucket starting at start.
+func (g Granularity) End(start time.Time) time.Time {
+	switch g {
+	case Hourly:
+		return start.Add(time.Hour)
+	case Daily:
+		return start.AddDate(0, 0, 1)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
+
+// Name returns the directory name of the bucket containing t, e.g. 2026-10-18T06, 2026-10-18 or 2026-10.
+func (g Granularity) Name(t time.Time) string {
+	return g.Start(t).Format(granularityLayouts[g])
+}
+
+// parse returns the start of the bucket named name.
+func (g Granularity) parse(name string) (time.Time, error) {
+	return time.ParseInLocation(granularityLayouts[g], name, time.UTC)
+}
+
+// PartitionOptions configures a PartitionedDB.
+type PartitionOptions struct {
+	// Granularity is the length of the time bucket of each partition.
+	Granularity Granularity
+	// Timestamp extracts the time of a record, which picks its partition.
+	Timestamp func(*KeyValue) (time.Time, error)
+	// Retention is how long partitions are kept after their bucket ends. Partitions are kept forever when 0.
+	Retention time.Duration
+	// MaxOpen is the number of partitions kept open at once. The least recently used idle partitions are closed
+	// beyond it. Defaults to 4.
+	MaxOpen int
+}
+
+// Partition is one time bucket of a PartitionedDB.
+type Partition struct {
+	Name  string    `json:"name"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Dir   string    `json:"dir"`
+}
+
+// errPartitionDropped is returned by acquire for the partitions dropped by EnforceRetention, which are not recreated.
+var errPartitionDropped = errors.New("partition dropped by retention")
+
+type openPartition struct {
+	db       *badger.DB
+	err      error
+	refs     int
+	lastUsed time.Time
+	// ready is closed once the partition is opened or failed to open
+	ready chan struct{}
+}
+
+// PartitionedDB routes records to one Badger per time bucket under a base directory. Partitions are opened when
+// first used and closed when idle, and expired partitions are dropped whole.
+type PartitionedDB struct {
+	base string
+	opts PartitionOptions
+
+	mu   sync.Mutex
+	open map[string]*openPartition
+	// busy holds the partitions being closed or removed, whose channel is closed once done
+	busy map[string]chan struct{}
+	// cutoff is the end of the newest bucket dropped by EnforceRetention
+	cutoff time.Time
+}
+
+// OpenPartitioned creates a PartitionedDB over the partitions under base. No partition is opened until it is used.
+func OpenPartitioned(base string, opts PartitionOptions) (*PartitionedDB, error) {
+	if opts.Timestamp == nil {
+		return nil, fmt.Errorf("a Timestamp function is required")
+	}
+	if opts.MaxOpen <= 0 {
+		opts.MaxOpen = 4
+	}
+	if mkdirErr := os.MkdirAll(base, os.ModePerm); mkdirErr != nil {
+		return nil, mkdirErr
+	}
+	return &PartitionedDB{
+		base: base,
+		opts: opts,
+		open: make(map[string]*openPartition),
+		busy: make(map[string]chan struct{}),
+	}, nil
+}
+
+// Partitions returns the partitions under the base directory in time order.
+func (p *PartitionedDB) Partitions() ([]Partition, error) {
+	files, err := ioutil.ReadDir(p.base)
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]Partition, 0)
+	for _, f := range files {
+		if !f.IsDir() {
+			continue
+		}
+		start, err := p.opts.Granularity.parse(f.Name())
+		if err != nil {
+			continue
+		}
+		partitions = append(partitions, Partition{
+			Name:  f.Name(),
+			Start: start,
+			End:   p.opts.Granularity.End(start),
+			Dir:   path.Join(p.base, f.Name()),
+		})
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].Start.Before(partitions[j].Start) })
+	return partitions, nil
+}
+
+// lockIdle locks p.mu once the partition named name is not being closed or removed.
+func (p *PartitionedDB) lockIdle(name string) {
+	p.mu.Lock()
+	for {
+		busy, ok := p.busy[name]
+		if !ok {
+			return
+		}
+		p.mu.Unlock()
+		<-busy
+		p.mu.Lock()
+	}
+}
+
+// markBusy marks the partition named name as being closed or removed while p.mu is held, and returns the function
+// that clears the mark.
+func (p *PartitionedDB) markBusy(name string) func() {
+	busy := make(chan struct{})
+	p.busy[name] = busy
+	return func() {
+		p.mu.Lock()
+		delete(p.busy, name)
+		p.mu.Unlock()
+		close(busy)
+	}
+}
+
+// acquire returns the Badger of the partition named name, opening it when needed. The partition stays open until
+// release is called. Opening replays the value log, so it happens without holding p.mu and only callers of the
+// same partition wait for it.
+func (p *PartitionedDB) acquire(name string) (*badger.DB, error) {
+	p.lockIdle(name)
+	op, ok := p.open[name]
+	if ok {
+		op.refs++
+		op.lastUsed = time.Now()
+		p.mu.Unlock()
+		<-op.ready
+		return op.db, op.err
+	}
+	if start, err := p.opts.Granularity.parse(name); err == nil && !p.opts.Granularity.End(start).After(p.cutoff) {
+		p.mu.Unlock()
+		return nil, errPartitionDropped
+	}
+	op = &openPartition{refs: 1, lastUsed: time.Now(), ready: make(chan struct{})}
+	p.open[name] = op
+	p.mu.Unlock()
+
+	op.db, op.err = Open(path.Join(p.base, name))
+	if op.err != nil {
+		p.mu.Lock()
+		delete(p.open, name)
+		p.mu.Unlock()
+	}
+	close(op.ready)
+	return op.db, op.err
+}
+
+// release marks a partition returned by acquire as unused and closes the least recently used idle partitions
+// beyond MaxOpen.
+func (p *PartitionedDB) release(name string) {
+	p.mu.Lock()
+	if op, ok := p.open[name]; ok {
+		op.refs--
+	}
+	closing := make(map[string]*badger.DB)
+	for len(p.open) > p.opts.MaxOpen {
+		var lru string
+		for n, op := range p.open {
+			if op.refs == 0 && (lru == "" || op.lastUsed.Before(p.open[lru].lastUsed)) {
+				lru = n
+			}
+		}
+		if lru == "" {
+			break
+		}
+		closing[lru] = p.open[lru].db
+		delete(p.open, lru)
+	}
+	done := make([]func(), 0, len(closing))
+	for n := range closing {
+		done = append(done, p.markBusy(n))
+	}
+	p.mu.Unlock()
+
+	for _, db := range closing {
+		db.Close()
+	}
+	for _, fn := range done {
+		fn()
+	}
+}
+
+// Close closes every open partition.
+func (p *PartitionedDB) Close() error {
+	p.mu.Lock()
+	open := p.open
+	p.open = make(map[string]*openPartition)
+	p.mu.Unlock()
+
+	var firstErr error
+	for _, op := range open {
+		<-op.ready
+		if op.err != nil {
+			continue
+		}
+		if err := op.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// WriteBatch splits kvs by partition and commits each part to its partition, so a PartitionedDB is a Sink.
+// Records of partitions that EnforceRetention already dropped are not written, so that late records do not
+// recreate them, and are reported in the error of the batch.
+func (p *PartitionedDB) WriteBatch(kvs []KeyValue, done func(int32, error)) {
+	p.mu.Lock()
+	cutoff := p.cutoff
+	p.mu.Unlock()
+
+	parts := make(map[string][]KeyValue)
+	expired := 0
+	for i := range kvs {
+		t, err := p.opts.Timestamp(&kvs[i])
+		if err != nil {
+			done(0, fmt.Errorf("key %q: %v", kvs[i].Key, err))
+			return
+		}
+		if !p.opts.Granularity.End(p.opts.Granularity.Start(t)).After(cutoff) {
+			expired++
+			continue
+		}
+		name := p.opts.Granularity.Name(t)
+		parts[name] = append(parts[name], kvs[i])
+	}
+
+	var written int32
+	errs := make([]string, 0)
+	if expired > 0 {
+		errs = append(errs, fmt.Sprintf("%v records in partitions dropped by retention before %v", expired,
+			cutoff.Format(time.RFC3339)))
+	}
+	for name, part := range parts {
+		db, err := p.acquire(name)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%v: %v", name, err))
+			continue
+		}
+		result := make(chan error, 1)
+		commitBatch(db, part, ConflictOverwrite, func(n int32, err error) {
+			written += n
+			result <- err
+		})
+		if err := <-result; err != nil {
+			errs = append(errs, fmt.Sprintf("%v: %v", name, err))
+		}
+		p.release(name)
+	}
+
+	if len(errs) > 0 {
+		done(written, fmt.Errorf("%v", strings.Join(errs, "; ")))
+		return
+	}
+	done(written, nil)
+}
+
+// WriteStream translates the lines of reader into key/values and writes them into their partitions.
+func (p *PartitionedDB) WriteStream(reader io.Reader, batchSize int, lineToKeyValue func(string) (*KeyValue, error)) error {
+	_, err := WriteStreamTo(context.Background(), reader, p, batchSize, lineToKeyValue, nil)
+	return err
+}
+
+// Get returns the value of key in the partition of at. It returns badger.ErrKeyNotFound when the key or the
+// partition does not exist.
+func (p *PartitionedDB) Get(at time.Time, key []byte) ([]byte, error) {
+	name := p.opts.Granularity.Name(at)
+	if _, err := os.Stat(path.Join(p.base, name)); os.IsNotExist(err) {
+		return nil, badger.ErrKeyNotFound
+	}
+
+	db, err := p.acquire(name)
+	if err == errPartitionDropped {
+		return nil, badger.ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer p.release(name)
+	return Get(db, key)
+}
+
+// Query calls fn for each key/value with prefix whose timestamp is in [from, to), reading the partitions that
+// overlap the window in time order and each partition in key order.
+func (p *PartitionedDB) Query(from, to time.Time, prefix []byte, fn func(*KeyValue) error) error {
+	partitions, err := p.Partitions()
+	if err != nil {
+		return err
+	}
+
+	for _, partition := range partitions {
+		if !partition.End.After(from) || !partition.Start.Before(to) {
+			continue
+		}
+		db, err := p.acquire(partition.Name)
+		if err == errPartitionDropped {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		_, err = Scan(db, ScanOptions{Prefix: prefix}, func(kv *KeyValue) error {
+			t, err := p.opts.Timestamp(kv)
+			if err != nil {
+				return fmt.Errorf("key %q: %v", kv.Key, err)
+			}
+			if t.Before(from) || !t.Before(to) {
+				return nil
+			}
+			return fn(kv)
+		})
+		p.release(partition.Name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnforceRetention drops the partitions whose bucket ended more than Retention before now by removing their
+// directories, and returns the dropped partitions. Partitions in use are kept until a later call. WriteBatch rejects
+// the records of dropped partitions older than the first partition kept.
+func (p *PartitionedDB) EnforceRetention(now time.Time) ([]Partition, error) {
+	dropped := make([]Partition, 0)
+	if p.opts.Retention <= 0 {
+		return dropped, nil
+	}
+
+	partitions, err := p.Partitions()
+	if err != nil {
+		return nil, err
+	}
+	cutoff := now.Add(-p.opts.Retention)
+	// Late records are rejected up to the end of the partitions dropped from the oldest one on, so a partition
+	// kept because it is in use, and every partition after it, still accept them
+	skipped := false
+	for _, partition := range partitions {
+		if partition.End.After(cutoff) {
+			continue
+		}
+
+		p.lockIdle(partition.Name)
+		op, ok := p.open[partition.Name]
+		if ok && op.refs > 0 {
+			skipped = true
+			p.mu.Unlock()
+			continue
+		}
+		if !skipped && partition.End.After(p.cutoff) {
+			p.cutoff = partition.End
+		}
+		delete(p.open, partition.Name)
+		done := p.markBusy(partition.Name)
+		p.mu.Unlock()
+
+		// Closing and removing happen without holding p.mu, and acquire waits for them on this partition only
+		if ok {
+			op.db.Close()
+		}
+		err := os.RemoveAll(partition.Dir)
+		done()
+		if err != nil {
+			return dropped, err
+		}
+
+		DefaultLogger.Log(LevelInfo, "Dropped partition", "partition", partition.Name)
+		dropped = append(dropped, partition)
+	}
+
+	// Every expired partition is gone, including ones dropped before that are no longer listed
+	if !skipped {
+		p.mu.Lock()
+		if end := p.opts.Granularity.Start(cutoff); end.After(p.cutoff) {
+			p.cutoff = end
+		}
+		p.mu.Unlock()
+	}
+	return dropped, nil
+}
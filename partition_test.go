@@ -0,0 +1,211 @@
+package badgerutils
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger"
+	"github.com/stretchr/testify/require"
+)
+
+// keyTimestamp reads the time of keys of the form <id>@<yyyymmddThhmmssZ>.
+func keyTimestamp(kv *KeyValue) (time.Time, error) {
+	i := bytes.IndexByte(kv.Key, '@')
+	return time.Parse("20060102T150405Z", string(kv.Key[i+1:]))
+}
+
+func TestGranularity(t *testing.T) {
+	at := time.Date(2026, 10, 18, 6, 30, 0, 0, time.UTC)
+	require.Equal(t, "2026-10-18T06", Hourly.Name(at))
+	require.Equal(t, "2026-10-18", Daily.Name(at))
+	require.Equal(t, "2026-10", Monthly.Name(at))
+	require.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), Monthly.End(Monthly.Start(at)))
+
+	start, err := Daily.parse("2026-10-18")
+	require.Nil(t, err)
+	require.Equal(t, Daily.Start(at), start)
+}
+
+func TestPartitionedDB(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	base := path.Join(tmpDir, "partitioned")
+	p, err := OpenPartitioned(base, PartitionOptions{
+		Granularity: Daily,
+		Timestamp:   keyTimestamp,
+		Retention:   48 * time.Hour,
+		MaxOpen:     2,
+	})
+	require.Nil(t, err)
+	defer p.Close()
+
+	reader := strings.NewReader(`a@20261015T100000Z:1
+b@20261016T100000Z:2
+a@20261017T100000Z:3
+b@20261017T230000Z:4
+a@20261018T010000Z:5`)
+	require.Nil(t, p.WriteStream(reader, 2, csvToKeyValue))
+	require.True(t, len(p.open) <= 2)
+
+	partitions, err := p.Partitions()
+	require.Nil(t, err)
+	names := make([]string, 0)
+	for _, partition := range partitions {
+		names = append(names, partition.Name)
+	}
+	require.Equal(t, []string{"2026-10-15", "2026-10-16", "2026-10-17", "2026-10-18"}, names)
+
+	value, err := p.Get(time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC), []byte("b@20261017T230000Z"))
+	require.Nil(t, err)
+	require.Equal(t, "4", string(value))
+	_, err = p.Get(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), []byte("a"))
+	require.Equal(t, badger.ErrKeyNotFound, err)
+
+	// The window spans partitions and excludes records outside it in the boundary partitions
+	values := make([]string, 0)
+	from := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 10, 18, 1, 0, 0, 0, time.UTC)
+	require.Nil(t, p.Query(from, to, nil, func(kv *KeyValue) error {
+		values = append(values, string(kv.Value))
+		return nil
+	}))
+	require.Equal(t, []string{"3", "4"}, values)
+
+	values = values[:0]
+	require.Nil(t, p.Query(time.Time{}, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), []byte("a"), func(kv *KeyValue) error {
+		values = append(values, string(kv.Value))
+		return nil
+	}))
+	require.Equal(t, []string{"1", "3", "5"}, values)
+
+	dropped, err := p.EnforceRetention(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+	require.Nil(t, err)
+	require.Equal(t, 2, len(dropped))
+	require.Equal(t, "2026-10-15", dropped[0].Name)
+	require.Equal(t, "2026-10-16", dropped[1].Name)
+	_, err = os.Stat(path.Join(base, "2026-10-16"))
+	require.True(t, os.IsNotExist(err))
+
+	partitions, err = p.Partitions()
+	require.Nil(t, err)
+	require.Equal(t, 2, len(partitions))
+
+	// Late records of dropped partitions are reported instead of recreating them
+	err = p.WriteStream(strings.NewReader("a@20261016T120000Z:late\nc@20261018T050000Z:6"), 10, csvToKeyValue)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "1 records in partitions dropped by retention before 2026-10-17T00:00:00Z")
+	_, err = os.Stat(path.Join(base, "2026-10-16"))
+	require.True(t, os.IsNotExist(err))
+	_, err = p.Get(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC), []byte("a@20261016T120000Z"))
+	require.Equal(t, badger.ErrKeyNotFound, err)
+	value, err = p.Get(time.Date(2026, 10, 18, 5, 0, 0, 0, time.UTC), []byte("c@20261018T050000Z"))
+	require.Nil(t, err)
+	require.Equal(t, "6", string(value))
+}
+
+func TestPartitionedDBRetentionInUse(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	base := path.Join(tmpDir, "partitioned")
+	p, err := OpenPartitioned(base, PartitionOptions{
+		Granularity: Daily,
+		Timestamp:   keyTimestamp,
+		Retention:   48 * time.Hour,
+	})
+	require.Nil(t, err)
+	defer p.Close()
+
+	reader := strings.NewReader(`a@20261014T100000Z:1
+a@20261015T100000Z:2
+a@20261016T100000Z:3
+a@20261018T100000Z:4`)
+	require.Nil(t, p.WriteStream(reader, 10, csvToKeyValue))
+
+	// A partition in use is kept, and only the partitions before it move the cutoff of late records
+	_, err = p.acquire("2026-10-15")
+	require.Nil(t, err)
+	dropped, err := p.EnforceRetention(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+	require.Nil(t, err)
+	require.Equal(t, 2, len(dropped))
+	require.Equal(t, "2026-10-14", dropped[0].Name)
+	require.Equal(t, "2026-10-16", dropped[1].Name)
+	p.release("2026-10-15")
+
+	err = p.WriteStream(strings.NewReader("a@20261014T120000Z:late\nb@20261015T120000Z:5\nb@20261016T120000Z:6"), 10,
+		csvToKeyValue)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "1 records in partitions dropped by retention before 2026-10-15T00:00:00Z")
+	value, err := p.Get(time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC), []byte("b@20261015T120000Z"))
+	require.Nil(t, err)
+	require.Equal(t, "5", string(value))
+	value, err = p.Get(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC), []byte("b@20261016T120000Z"))
+	require.Nil(t, err)
+	require.Equal(t, "6", string(value))
+
+	// Once nothing is in use every expired partition is dropped, and the cutoff moves to the retention cutoff
+	dropped, err = p.EnforceRetention(time.Date(2026, 10, 19, 6, 0, 0, 0, time.UTC))
+	require.Nil(t, err)
+	require.Equal(t, 2, len(dropped))
+	err = p.WriteStream(strings.NewReader("b@20261016T130000Z:7"), 10, csvToKeyValue)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "before 2026-10-17T00:00:00Z")
+}
+
+func TestPartitionedDBConcurrent(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	// With one open partition, writers of different partitions keep closing and reopening each other's partition
+	p, err := OpenPartitioned(path.Join(tmpDir, "partitioned"), PartitionOptions{
+		Granularity: Hourly,
+		Timestamp:   keyTimestamp,
+		MaxOpen:     1,
+	})
+	require.Nil(t, err)
+	defer p.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for hour := 0; hour < 4; hour++ {
+		wg.Add(1)
+		go func(hour int) {
+			defer wg.Done()
+			lines := make([]string, 0)
+			for i := 0; i < 50; i++ {
+				lines = append(lines, fmt.Sprintf("k%02d@20261018T0%v%02d00Z:%v", i, hour, i, i))
+			}
+			errs <- p.WriteStream(strings.NewReader(strings.Join(lines, "\n")), 5, csvToKeyValue)
+		}(hour)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.Nil(t, err)
+	}
+
+	count := 0
+	require.Nil(t, p.Query(time.Time{}, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), nil, func(*KeyValue) error {
+		count++
+		return nil
+	}))
+	require.Equal(t, 200, count)
+	require.True(t, len(p.open) <= 1)
+}
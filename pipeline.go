@@ -0,0 +1,322 @@
+package badgerutils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pipeline reads key/values from a source, passes them through a chain of transforms and writes them in batches
+// to a Sink:
+//
+//	result, err := badgerutils.From(os.Stdin, csvToKeyValue).
+//		Filter(isValid).
+//		Prefix([]byte("spots/")).
+//		Dedup().
+//		To(badgerutils.NewBadgerSink(db))
+//
+// Batches are committed concurrently as in WriteStream, and hooks are called at the same stages.
+type Pipeline struct {
+	read      func(emit func(*KeyValue) error, reject func(string, error)) error
+	stages    []func(kv *KeyValue, emit func(*KeyValue) error) error
+	batchSize int
+	hooks     WriteHooks
+}
+
+// NewPipeline creates a Pipeline from a custom source. read calls emit for each key/value and returns the first
+// error returned by emit. It calls reject for a record that cannot be read before returning its error.
+func NewPipeline(read func(emit func(*KeyValue) error, reject func(record string, err error)) error) *Pipeline {
+	return &Pipeline{read: read, batchSize: 1000, hooks: NopHooks{}}
+}
+
+// From creates a Pipeline that reads lines from reader and translates them with lineToKeyValue. The pipeline stops
+// at the first line that cannot be translated.
+func From(reader io.Reader, lineToKeyValue func(string) (*KeyValue, error)) *Pipeline {
+	return NewPipeline(func(emit func(*KeyValue) error, reject func(string, error)) error {
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			line := scanner.Text()
+			kv, err := lineToKeyValue(line)
+			if err != nil {
+				reject(line, err)
+				return err
+			}
+			if err := emit(kv); err != nil {
+				return err
+			}
+		}
+		return scanner.Err()
+	})
+}
+
+// FromFrames creates a Pipeline that reads frames prefixed with their little-endian uint64 length, the framing of
+// Badger backups, and translates them with frameToKeyValue.
+func FromFrames(reader io.Reader, frameToKeyValue func([]byte) (*KeyValue, error)) *Pipeline {
+	return NewPipeline(func(emit func(*KeyValue) error, reject func(string, error)) error {
+		return readFrames(reader, func(frame []byte) error {
+			// readFrames reuses its buffer, and key/values are held until their batch is written
+			frame = append([]byte{}, frame...)
+			kv, err := frameToKeyValue(frame)
+			if err != nil {
+				reject(fmt.Sprintf("%x", frame), err)
+				return err
+			}
+			return emit(kv)
+		})
+	})
+}
+
+// FromSource creates a Pipeline that reads the key/values with prefix from source in key order, e.g. a BadgerSource
+// to copy from another Badger.
+func FromSource(source Source, prefix []byte) *Pipeline {
+	return NewPipeline(func(emit func(*KeyValue) error, reject func(string, error)) error {
+		return source.Iterate(prefix, emit)
+	})
+}
+
+// BatchSize sets the number of key/values written per batch. Defaults to 1000.
+func (p *Pipeline) BatchSize(n int) *Pipeline {
+	p.batchSize = n
+	return p
+}
+
+// Hooks sets the hooks called as the pipeline runs. hooks may be nil.
+func (p *Pipeline) Hooks(hooks WriteHooks) *Pipeline {
+	if hooks == nil {
+		hooks = NopHooks{}
+	}
+	p.hooks = hooks
+	return p
+}
+
+// FlatMap replaces each key/value with the key/values returned by fn, which may be none.
+func (p *Pipeline) FlatMap(fn func(*KeyValue) ([]KeyValue, error)) *Pipeline {
+	p.stages = append(p.stages, func(kv *KeyValue, emit func(*KeyValue) error) error {
+		kvs, err := fn(kv)
+		if err != nil {
+			return err
+		}
+		for i := range kvs {
+			if err := emit(&kvs[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return p
+}
+
+// Map replaces each key/value with the one returned by fn. The key/value is dropped when fn returns nil.
+func (p *Pipeline) Map(fn func(*KeyValue) (*KeyValue, error)) *Pipeline {
+	p.stages = append(p.stages, func(kv *KeyValue, emit func(*KeyValue) error) error {
+		kv, err := fn(kv)
+		if err != nil || kv == nil {
+			return err
+		}
+		return emit(kv)
+	})
+	return p
+}
+
+// Filter drops the key/values for which fn returns false.
+func (p *Pipeline) Filter(fn func(*KeyValue) bool) *Pipeline {
+	p.stages = append(p.stages, func(kv *KeyValue, emit func(*KeyValue) error) error {
+		if !fn(kv) {
+			return nil
+		}
+		return emit(kv)
+	})
+	return p
+}
+
+// Prefix prepends prefix to every key.
+func (p *Pipeline) Prefix(prefix []byte) *Pipeline {
+	return p.Map(func(kv *KeyValue) (*KeyValue, error) {
+		key := make([]byte, 0, len(prefix)+len(kv.Key))
+		key = append(append(key, prefix...), kv.Key...)
+		return &KeyValue{Key: key, Value: kv.Value}, nil
+	})
+}
+
+// Dedup drops key/values whose key has already been seen, keeping the first. Every key seen is held in memory.
+func (p *Pipeline) Dedup() *Pipeline {
+	seen := make(map[string]struct{})
+	return p.Filter(func(kv *KeyValue) bool {
+		if _, ok := seen[string(kv.Key)]; ok {
+			return false
+		}
+		seen[string(kv.Key)] = struct{}{}
+		return true
+	})
+}
+
+// To runs the pipeline and writes its key/values to sink.
+func (p *Pipeline) To(sink Sink) (WriteResult, error) {
+	return p.ToContext(context.Background(), sink)
+}
+
+// ToContext runs the pipeline and writes its key/values to sink. Reading stops with ctx.Err() once ctx is done.
+func (p *Pipeline) ToContext(ctx context.Context, sink Sink) (result WriteResult, err error) {
+	hooks := p.hooks
+	start := time.Now()
+	defer func() {
+		result.Elapsed = time.Since(start)
+		result.Err = err
+		hooks.OnComplete(ctx, result)
+	}()
+
+	w := newBatchWriter(sink, p.batchSize)
+	w.ctx = ctx
+	w.hooks = hooks
+	defer func() {
+		result.Records = int(w.kvCount.get())
+		result.Batches = w.batches
+	}()
+
+	emit, reject := p.chain(ctx, &result, func(kv *KeyValue) error {
+		w.add(*kv)
+		return nil
+	})
+
+	// Write remaining key/values even if reading failed so no batches are left running
+	readErr := p.read(emit, reject)
+	if flushErr := w.flush(); flushErr != nil && readErr == nil {
+		return result, flushErr
+	}
+	if readErr != nil {
+		return result, readErr
+	}
+
+	DefaultLogger.Log(LevelInfo, "Inserted records", "records", w.kvCount.get(), "elapsed", time.Since(start))
+	return result, nil
+}
+
+// chain returns the emit and reject functions passed to the source, which run the stages of the pipeline and then
+// write with last, and count rejected records in result.
+func (p *Pipeline) chain(ctx context.Context, result *WriteResult, last func(*KeyValue) error) (func(*KeyValue) error,
+	func(string, error)) {
+	// Chain the stages from the last so each one emits into the next
+	emit := last
+	for i := len(p.stages) - 1; i >= 0; i-- {
+		stage, next := p.stages[i], emit
+		emit = func(kv *KeyValue) error {
+			return stage(kv, next)
+		}
+	}
+
+	first := emit
+	emit = func(kv *KeyValue) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		p.hooks.OnRecordParsed(ctx, kv)
+		return first(kv)
+	}
+	reject := func(record string, err error) {
+		DefaultMetrics.IncCounter(MetricRecordsRejected, 1)
+		result.Rejected++
+		p.hooks.OnRejected(ctx, record, err)
+	}
+	return emit, reject
+}
+
+// exportSink writes key/values as lines to a writer.
+type exportSink struct {
+	mu             sync.Mutex
+	writer         io.Writer
+	keyValueToLine func(*KeyValue) (string, error)
+}
+
+// NewExportSink returns a Sink that writes each key/value to writer as a line translated with keyValueToLine.
+// Batches are written whole but in the order they are committed, which may differ from the order they were read.
+func NewExportSink(writer io.Writer, keyValueToLine func(*KeyValue) (string, error)) Sink {
+	return &exportSink{writer: writer, keyValueToLine: keyValueToLine}
+}
+
+func (s *exportSink) WriteBatch(kvs []KeyValue, done func(int32, error)) {
+	var buf bytes.Buffer
+	for i := range kvs {
+		line, err := s.keyValueToLine(&kvs[i])
+		if err != nil {
+			done(0, err)
+			return
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+
+	s.mu.Lock()
+	_, err := s.writer.Write(buf.Bytes())
+	s.mu.Unlock()
+	if err != nil {
+		done(0, err)
+		return
+	}
+	done(int32(len(kvs)), nil)
+}
+
+// teeSink writes every batch to several sinks.
+type teeSink struct {
+	sinks []Sink
+}
+
+// NewTeeSink returns a Sink that writes every batch to each of sinks concurrently. Each sink after the first gets
+// its own copy of the batch, so sinks may keep or modify their key/values. It reports the number of key/values
+// written to the first sink, and the errors of every sink that failed.
+func NewTeeSink(sinks ...Sink) Sink {
+	return &teeSink{sinks: sinks}
+}
+
+func (s *teeSink) WriteBatch(kvs []KeyValue, done func(int32, error)) {
+	var wg sync.WaitGroup
+	written := make([]int32, len(s.sinks))
+	errs := make([]error, len(s.sinks))
+	wg.Add(len(s.sinks))
+	// The copies are made before any sink starts changing the batch
+	batches := make([][]KeyValue, len(s.sinks))
+	for i := range s.sinks {
+		batches[i] = kvs
+		if i > 0 {
+			batches[i] = copyKeyValues(kvs)
+		}
+	}
+	for i, sink := range s.sinks {
+		go func(i int, sink Sink) {
+			sink.WriteBatch(batches[i], func(n int32, err error) {
+				written[i], errs[i] = n, err
+				wg.Done()
+			})
+		}(i, sink)
+	}
+	wg.Wait()
+
+	msgs := make([]string, 0)
+	for i, err := range errs {
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("sink %v: %v", i, err))
+		}
+	}
+	if len(msgs) > 0 {
+		done(0, fmt.Errorf("%v", strings.Join(msgs, "; ")))
+		return
+	}
+	if len(written) == 0 {
+		done(0, nil)
+		return
+	}
+	done(written[0], nil)
+}
+
+// copyKeyValues returns a copy of kvs that shares none of their keys or values.
+func copyKeyValues(kvs []KeyValue) []KeyValue {
+	c := make([]KeyValue, len(kvs))
+	for i, kv := range kvs {
+		c[i] = KeyValue{Key: append([]byte{}, kv.Key...), Value: append([]byte{}, kv.Value...)}
+	}
+	return c
+}
@@ -0,0 +1,109 @@
+package badgerutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipeline(t *testing.T) {
+	m := NewMemoryDB()
+	reader := strings.NewReader("a:1\nb:2\na:3\nskip:4\nc:5")
+	result, err := From(reader, csvToKeyValue).
+		BatchSize(2).
+		Filter(func(kv *KeyValue) bool { return string(kv.Key) != "skip" }).
+		Dedup().
+		Map(func(kv *KeyValue) (*KeyValue, error) {
+			if string(kv.Key) == "c" {
+				return nil, nil
+			}
+			return &KeyValue{Key: kv.Key, Value: append([]byte("v"), kv.Value...)}, nil
+		}).
+		FlatMap(func(kv *KeyValue) ([]KeyValue, error) {
+			return []KeyValue{*kv, {Key: append(append([]byte{}, kv.Key...), '2'), Value: kv.Value}}, nil
+		}).
+		Prefix([]byte("p/")).
+		To(m)
+	require.Nil(t, err)
+	require.Equal(t, 4, result.Records)
+	require.Equal(t, 2, result.Batches)
+
+	kvs := make([]string, 0)
+	require.Nil(t, m.Iterate(nil, func(kv *KeyValue) error {
+		kvs = append(kvs, fmt.Sprintf("%s=%s", kv.Key, kv.Value))
+		return nil
+	}))
+	require.Equal(t, []string{"p/a=v1", "p/a2=v1", "p/b=v2", "p/b2=v2"}, kvs)
+
+	// Copy from a source into a tee of a MemoryDB and an export writer
+	copied := NewMemoryDB()
+	var exported bytes.Buffer
+	result, err = FromSource(m, []byte("p/a")).To(NewTeeSink(copied, NewExportSink(&exported, keyValueToCsv)))
+	require.Nil(t, err)
+	require.Equal(t, 2, result.Records)
+	require.Equal(t, 2, copied.Len())
+	lines := strings.Split(strings.TrimSpace(exported.String()), "\n")
+	sort.Strings(lines)
+	require.Equal(t, []string{"p/a2:v1", "p/a:v1"}, lines)
+}
+
+// mutatingSink overwrites the key/values of each batch it writes.
+type mutatingSink struct{}
+
+func (mutatingSink) WriteBatch(kvs []KeyValue, done func(int32, error)) {
+	for i := range kvs {
+		for j := range kvs[i].Value {
+			kvs[i].Value[j] = 'x'
+		}
+		kvs[i].Key = []byte("mutated")
+	}
+	done(int32(len(kvs)), nil)
+}
+
+func TestTeeSinkCopies(t *testing.T) {
+	for _, mutatingFirst := range []bool{true, false} {
+		m := NewMemoryDB()
+		sinks := []Sink{m, mutatingSink{}}
+		if mutatingFirst {
+			sinks = []Sink{mutatingSink{}, m}
+		}
+		result, err := From(strings.NewReader("a:1\nb:2\nc:3"), csvToKeyValue).BatchSize(2).To(NewTeeSink(sinks...))
+		require.Nil(t, err)
+		require.Equal(t, 3, result.Records)
+
+		kvs := make([]string, 0)
+		require.Nil(t, m.Iterate(nil, func(kv *KeyValue) error {
+			kvs = append(kvs, fmt.Sprintf("%s=%s", kv.Key, kv.Value))
+			return nil
+		}))
+		require.Equal(t, []string{"a=1", "b=2", "c=3"}, kvs)
+	}
+}
+
+func TestPipelineFromFrames(t *testing.T) {
+	var frames bytes.Buffer
+	for _, frame := range []string{"k1=v1", "k2=v2", "invalid"} {
+		binary.Write(&frames, binary.LittleEndian, uint64(len(frame)))
+		frames.WriteString(frame)
+	}
+
+	m := NewMemoryDB()
+	result, err := FromFrames(&frames, func(frame []byte) (*KeyValue, error) {
+		kv := bytes.SplitN(frame, []byte("="), 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid frame %q", frame)
+		}
+		return &KeyValue{Key: kv[0], Value: kv[1]}, nil
+	}).To(m)
+	require.NotNil(t, err)
+	require.Equal(t, 1, result.Rejected)
+	require.Equal(t, 2, result.Records)
+	value, err := m.Get([]byte("k2"))
+	require.Nil(t, err)
+	require.Equal(t, "v2", string(value))
+}
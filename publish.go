@@ -0,0 +1,212 @@
+package badgerutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CurrentLink is the name of the symlink that points readers at the published version under a base directory.
+const CurrentLink = "current"
+
+// VersionLayout formats the default version names of BuildAndPublish.
+const VersionLayout = "2006-01-02T150405"
+
+// PublishOptions configures BuildAndPublish.
+type PublishOptions struct {
+	// Version names the directory of the new version. Defaults to the current UTC time formatted with VersionLayout.
+	Version string
+	// MinRecords fails verification when the new version has fewer keys.
+	MinRecords int
+	// Digest, when set, fails verification unless the new version has the same digest, e.g. one computed with
+	// DigestStream from the input.
+	Digest *DBDigest
+	// Verify is an additional check of the closed directory of the new version.
+	Verify func(dir string) error
+	// Keep is the number of versions kept, including the published one. Every version is kept when 0.
+	Keep int
+}
+
+// BuildAndPublish builds a new version of a dataset under base and publishes it by atomically pointing the current
+// symlink at it, so readers opening base/current see either the old or the new version but never a partial one.
+//
+// build writes the new version into dir, which it must close before returning. The version is then verified and
+// published, and versions beyond opts.Keep are removed from the oldest. A version that fails to build or verify is
+// removed and current is left untouched, as is a version that fails to publish. It returns the directory of the new version.
+func BuildAndPublish(base string, opts PublishOptions, build func(dir string) error) (string, error) {
+	version := opts.Version
+	if version == "" {
+		version = time.Now().UTC().Format(VersionLayout)
+	}
+	if err := validateVersion(version); err != nil {
+		return "", err
+	}
+	dir := path.Join(base, version)
+	if _, err := os.Stat(dir); err == nil {
+		return "", fmt.Errorf("version %v already exists", version)
+	}
+	if mkdirErr := os.MkdirAll(dir, os.ModePerm); mkdirErr != nil {
+		return "", mkdirErr
+	}
+
+	if err := build(dir); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("building %v: %v", version, err)
+	}
+	if err := verifyVersion(dir, opts); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("verifying %v: %v", version, err)
+	}
+	if err := Publish(base, version); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("publishing %v: %v", version, err)
+	}
+	DefaultLogger.Log(LevelInfo, "Published version", "version", version)
+
+	if opts.Keep > 0 {
+		if err := pruneVersions(base, opts.Keep); err != nil {
+			return dir, err
+		}
+	}
+	return dir, nil
+}
+
+// validateVersion rejects version names that are not a single directory directly under the base directory.
+func validateVersion(version string) error {
+	switch {
+	case version == "" || version == "." || version == "..":
+		return fmt.Errorf("invalid version name %q", version)
+	case version == CurrentLink || version == CurrentLink+".tmp":
+		return fmt.Errorf("version cannot be named %v", version)
+	case strings.ContainsAny(version, "/"+string(os.PathSeparator)):
+		return fmt.Errorf("version name %q cannot contain a path separator", version)
+	}
+	return nil
+}
+
+func verifyVersion(dir string, opts PublishOptions) error {
+	if opts.MinRecords > 0 || opts.Digest != nil {
+		digest, err := Digest(dir, nil)
+		if err != nil {
+			return err
+		}
+		if digest.Count < opts.MinRecords {
+			return fmt.Errorf("%v records, expected at least %v", digest.Count, opts.MinRecords)
+		}
+		if opts.Digest != nil && *digest != *opts.Digest {
+			return fmt.Errorf("digest %v of %v records does not match expected digest %v of %v records",
+				digest.Checksum, digest.Count, opts.Digest.Checksum, opts.Digest.Count)
+		}
+	}
+	if opts.Verify != nil {
+		return opts.Verify(dir)
+	}
+	return nil
+}
+
+// Versions returns the versions under base in name order, which is build order for the default version names.
+func Versions(base string) ([]string, error) {
+	files, err := ioutil.ReadDir(base)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0)
+	for _, f := range files {
+		if f.IsDir() && f.Name() != CurrentLink {
+			versions = append(versions, f.Name())
+		}
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
+
+// CurrentVersion returns the version the current symlink under base points at, or "" when none is published.
+func CurrentVersion(base string) (string, error) {
+	target, err := os.Readlink(path.Join(base, CurrentLink))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return path.Base(target), nil
+}
+
+// Publish atomically points the current symlink under base at version by renaming a new symlink over it.
+func Publish(base, version string) error {
+	if err := validateVersion(version); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path.Join(base, version)); err != nil || !info.IsDir() {
+		return fmt.Errorf("version %v does not exist", version)
+	}
+
+	tmp := path.Join(base, CurrentLink+".tmp")
+	os.Remove(tmp)
+	if err := os.Symlink(version, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path.Join(base, CurrentLink)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Rollback points the current symlink under base at the version before the current one and returns it.
+func Rollback(base string) (string, error) {
+	current, err := CurrentVersion(base)
+	if err != nil {
+		return "", err
+	}
+	versions, err := Versions(base)
+	if err != nil {
+		return "", err
+	}
+
+	i := sort.SearchStrings(versions, current)
+	if current == "" || i == 0 {
+		return "", fmt.Errorf("no version before %q", current)
+	}
+	previous := versions[i-1]
+	if err := Publish(base, previous); err != nil {
+		return "", err
+	}
+	DefaultLogger.Log(LevelInfo, "Rolled back version", "from", current, "to", previous)
+	return previous, nil
+}
+
+// pruneVersions removes the oldest versions under base beyond keep, never removing the current version.
+func pruneVersions(base string, keep int) error {
+	current, err := CurrentVersion(base)
+	if err != nil {
+		return err
+	}
+	versions, err := Versions(base)
+	if err != nil {
+		return err
+	}
+
+	for _, version := range versions[:len(versions)-minInt(keep, len(versions))] {
+		if version == current {
+			continue
+		}
+		if err := os.RemoveAll(path.Join(base, version)); err != nil {
+			return err
+		}
+		DefaultLogger.Log(LevelInfo, "Removed version", "version", version)
+	}
+	return nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,101 @@
+package badgerutils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildAndPublish(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	base := path.Join(tmpDir, "dataset")
+	build := func(input string) func(string) error {
+		return func(dir string) error {
+			return WriteStream(strings.NewReader(input), dir, 2, csvToKeyValue)
+		}
+	}
+
+	input := "key1:value1\nkey2:value2"
+	digest, err := DigestStream(strings.NewReader(input), nil, csvToKeyValue)
+	require.Nil(t, err)
+	versionDir, err := BuildAndPublish(base, PublishOptions{Version: "v1", MinRecords: 2, Digest: digest, Keep: 2},
+		build(input))
+	require.Nil(t, err)
+	require.Equal(t, path.Join(base, "v1"), versionDir)
+
+	current, err := CurrentVersion(base)
+	require.Nil(t, err)
+	require.Equal(t, "v1", current)
+	records, err := readDB(path.Join(base, CurrentLink))
+	require.Nil(t, err)
+	require.Equal(t, 2, len(records))
+
+	// Versions that fail to build or verify are removed and current is left untouched
+	_, err = BuildAndPublish(base, PublishOptions{Version: "v2"}, func(string) error { return errors.New("failed") })
+	require.NotNil(t, err)
+	_, err = BuildAndPublish(base, PublishOptions{Version: "v2", MinRecords: 5}, build(input))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "2 records, expected at least 5")
+	_, err = BuildAndPublish(base, PublishOptions{Version: "v2", Digest: digest}, build("key1:changed\nkey2:value2"))
+	require.NotNil(t, err)
+	versions, err := Versions(base)
+	require.Nil(t, err)
+	require.Equal(t, []string{"v1"}, versions)
+	current, err = CurrentVersion(base)
+	require.Nil(t, err)
+	require.Equal(t, "v1", current)
+
+	_, err = BuildAndPublish(base, PublishOptions{Version: "v2", Keep: 2}, build("key3:value3"))
+	require.Nil(t, err)
+	_, err = BuildAndPublish(base, PublishOptions{Version: "v3", Keep: 2}, build("key4:value4"))
+	require.Nil(t, err)
+	versions, err = Versions(base)
+	require.Nil(t, err)
+	require.Equal(t, []string{"v2", "v3"}, versions)
+	_, err = BuildAndPublish(base, PublishOptions{Version: "v3"}, build("key4:value4"))
+	require.NotNil(t, err)
+
+	previous, err := Rollback(base)
+	require.Nil(t, err)
+	require.Equal(t, "v2", previous)
+	records, err = readDB(path.Join(base, CurrentLink))
+	require.Nil(t, err)
+	require.Equal(t, []sampleRecord{{Key: "key3", Value: "value3"}}, records)
+	_, err = Rollback(base)
+	require.NotNil(t, err)
+
+	require.Nil(t, Publish(base, "v3"))
+	current, err = CurrentVersion(base)
+	require.Nil(t, err)
+	require.Equal(t, "v3", current)
+	require.NotNil(t, Publish(base, "v1"))
+
+	// Version names must be a single directory under base
+	for _, version := range []string{"..", ".", "../escape", "a/b", CurrentLink} {
+		_, err = BuildAndPublish(base, PublishOptions{Version: version}, build(input))
+		require.NotNil(t, err, version)
+		require.NotNil(t, Publish(base, version), version)
+	}
+	_, err = os.Stat(path.Join(tmpDir, "escape"))
+	require.True(t, os.IsNotExist(err))
+
+	// A version that fails to publish is removed
+	failing := path.Join(tmpDir, "failing")
+	require.Nil(t, os.MkdirAll(path.Join(failing, CurrentLink, "file"), os.ModePerm))
+	_, err = BuildAndPublish(failing, PublishOptions{Version: "v1"}, build(input))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "publishing v1")
+	versions, err = Versions(failing)
+	require.Nil(t, err)
+	require.Equal(t, []string{}, versions)
+}
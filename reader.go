@@ -0,0 +1,152 @@
+package badgerutils
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/dgraph-io/badger"
+)
+
+// ScanOptions defines the range of keys read by Scan.
+type ScanOptions struct {
+	// Prefix limits the scan to keys with this prefix.
+	Prefix []byte
+	// Start is the first key to read. The scan starts at Prefix when Start is before it.
+	Start []byte
+	// End, when set, stops the scan before the first key at or after it.
+	End []byte
+	// Limit is the maximum number of key/values to read. There is no limit when 0.
+	Limit int
+	// KeysOnly skips reading values.
+	KeysOnly bool
+}
+
+// valid reports whether it is at a key within the prefix and before the end of opts.
+func (opts ScanOptions) valid(it *badger.Iterator) bool {
+	if !it.ValidForPrefix(opts.Prefix) {
+		return false
+	}
+	return opts.End == nil || bytes.Compare(it.Item().Key(), opts.End) < 0
+}
+
+// observeRead reports a read helper call that started at start and returned records.
+func observeRead(start time.Time, records int, err error) {
+	DefaultMetrics.IncCounter(MetricReads, 1)
+	DefaultMetrics.Observe(MetricReadTime, time.Since(start).Seconds())
+	if err != nil && err != badger.ErrKeyNotFound {
+		DefaultMetrics.IncCounter(MetricReadErrors, 1)
+	}
+	DefaultMetrics.IncCounter(MetricRecordsRead, float64(records))
+}
+
+// Get returns the value of key. It returns badger.ErrKeyNotFound when the key does not exist.
+func Get(db *badger.DB, key []byte) ([]byte, error) {
+	start := time.Now()
+	var value []byte
+	err := db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	records := 0
+	if err == nil {
+		records = 1
+	}
+	observeRead(start, records, err)
+	return value, err
+}
+
+// Put sets the value of key.
+func Put(db *badger.DB, key, value []byte) error {
+	return db.Update(func(txn *badger.Txn) error {
+		return txn.Set(key, value)
+	})
+}
+
+// Delete removes key.
+func Delete(db *badger.DB, key []byte) error {
+	return db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(key)
+	})
+}
+
+// Scan calls fn for each key/value in key order within the range defined by opts.
+// When the scan stops at opts.Limit it returns the key to pass as opts.Start to read the next page,
+// otherwise it returns nil.
+func Scan(db *badger.DB, opts ScanOptions, fn func(*KeyValue) error) ([]byte, error) {
+	startTime := time.Now()
+	count := 0
+	var next []byte
+	err := db.View(func(txn *badger.Txn) error {
+		iteratorOpts := badger.DefaultIteratorOptions
+		iteratorOpts.PrefetchValues = !opts.KeysOnly
+		it := txn.NewIterator(iteratorOpts)
+		defer it.Close()
+
+		start := opts.Start
+		if bytes.Compare(opts.Prefix, start) > 0 {
+			start = opts.Prefix
+		}
+
+		for it.Seek(start); opts.valid(it); it.Next() {
+			item := it.Item()
+			if opts.Limit > 0 && count == opts.Limit {
+				next = item.KeyCopy(nil)
+				return nil
+			}
+			kv := &KeyValue{Key: item.KeyCopy(nil)}
+			if !opts.KeysOnly {
+				value, err := item.ValueCopy(nil)
+				if err != nil {
+					return err
+				}
+				kv.Value = value
+			}
+			if err := fn(kv); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	observeRead(startTime, count, err)
+	return next, err
+}
+
+// Count returns the number of keys with the given prefix.
+func Count(db *badger.DB, prefix []byte) (int, error) {
+	start := time.Now()
+	count := 0
+	err := db.View(func(txn *badger.Txn) error {
+		iteratorOpts := badger.DefaultIteratorOptions
+		iteratorOpts.PrefetchValues = false
+		it := txn.NewIterator(iteratorOpts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			count++
+		}
+		return nil
+	})
+	observeRead(start, 0, err)
+	return count, err
+}
+
+// GC runs value log garbage collection with discardRatio until no more value log files can be rewritten.
+// It returns the number of value log files that were rewritten.
+func GC(db *badger.DB, discardRatio float64) (int, error) {
+	rewritten := 0
+	for {
+		err := db.RunValueLogGC(discardRatio)
+		if err == badger.ErrNoRewrite {
+			return rewritten, nil
+		}
+		if err != nil {
+			return rewritten, err
+		}
+		rewritten++
+	}
+}
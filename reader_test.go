@@ -0,0 +1,72 @@
+package badgerutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/dgraph-io/badger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadHelpers(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(path.Join(tmpDir, "path", "to", "db"))
+	require.Nil(t, err)
+	defer db.Close()
+
+	for _, key := range []string{"a1", "b1", "b2", "b3", "c1"} {
+		require.Nil(t, Put(db, []byte(key), []byte("value-"+key)))
+	}
+
+	value, err := Get(db, []byte("b2"))
+	require.Nil(t, err)
+	require.Equal(t, []byte("value-b2"), value)
+
+	require.Nil(t, Delete(db, []byte("b2")))
+	_, err = Get(db, []byte("b2"))
+	require.Equal(t, badger.ErrKeyNotFound, err)
+
+	count, err := Count(db, []byte("b"))
+	require.Nil(t, err)
+	require.Equal(t, 2, count)
+
+	kvs := make([]KeyValue, 0)
+	collect := func(kv *KeyValue) error {
+		kvs = append(kvs, *kv)
+		return nil
+	}
+
+	next, err := Scan(db, ScanOptions{Limit: 2}, collect)
+	require.Nil(t, err)
+	require.Equal(t, []byte("b3"), next)
+	require.Equal(t, []KeyValue{
+		{Key: []byte("a1"), Value: []byte("value-a1")},
+		{Key: []byte("b1"), Value: []byte("value-b1")},
+	}, kvs)
+
+	kvs = kvs[:0]
+	next, err = Scan(db, ScanOptions{Prefix: []byte("b"), Start: []byte("b2"), KeysOnly: true}, collect)
+	require.Nil(t, err)
+	require.Nil(t, next)
+	require.Equal(t, []KeyValue{{Key: []byte("b3")}}, kvs)
+
+	// Stopping at End is a normal end of the scan, not a read error
+	metrics := NewPrometheusMetrics()
+	DefaultMetrics = metrics
+	defer func() { DefaultMetrics = nopMetrics{} }()
+	kvs = kvs[:0]
+	next, err = Scan(db, ScanOptions{Start: []byte("b"), End: []byte("c1"), KeysOnly: true}, collect)
+	require.Nil(t, err)
+	require.Nil(t, next)
+	require.Equal(t, []KeyValue{{Key: []byte("b1")}, {Key: []byte("b3")}}, kvs)
+	require.Equal(t, float64(1), metrics.counters[MetricReads])
+	require.Equal(t, float64(0), metrics.counters[MetricReadErrors])
+	require.Equal(t, float64(2), metrics.counters[MetricRecordsRead])
+}
@@ -0,0 +1,581 @@
+package badgerutils
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger"
+)
+
+// RESPServer serves a subset of the Redis protocol (RESP) over a Badger so that existing Redis clients can use it
+// as a persistent store. Supported commands are GET, SET (with EX, PX, NX and XX), DEL, EXISTS, MGET, MSET,
+// SCAN (with MATCH and COUNT), INCR, INCRBY, DECR, DBSIZE, PING, ECHO, SELECT 0, COMMAND and QUIT.
+type RESPServer struct {
+	db    *badger.DB
+	conns *connTracker
+}
+
+// NewRESPServer creates a RESPServer over db.
+func NewRESPServer(db *badger.DB) *RESPServer {
+	return &RESPServer{db: db, conns: newConnTracker()}
+}
+
+// Serve accepts connections on l until Close is called.
+func (s *RESPServer) Serve(l net.Listener) error {
+	return s.conns.serve(l, s.handle)
+}
+
+// Close stops accepting connections and closes open connections.
+func (s *RESPServer) Close() error {
+	s.conns.close()
+	return nil
+}
+
+var errRESPQuit = errors.New("quit")
+
+// respError is an error reply sent to the client.
+type respError string
+
+func (e respError) Error() string {
+	return string(e)
+}
+
+func wrongArgs(cmd string) respError {
+	return respError(fmt.Sprintf("ERR wrong number of arguments for '%v' command", strings.ToLower(cmd)))
+}
+
+const errRESPSyntax = respError("ERR syntax error")
+const errRESPNotInteger = respError("ERR value is not an integer or out of range")
+const errRESPOverflow = respError("ERR increment or decrement would overflow")
+
+func (s *RESPServer) handle(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		args, err := readRESPCommand(r)
+		if err != nil {
+			if _, ok := err.(respError); ok {
+				writeRESP(w, err)
+				w.Flush()
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		reply, err := s.exec(strings.ToUpper(string(args[0])), args[1:])
+		if err == errRESPQuit {
+			writeRESP(w, "OK")
+			w.Flush()
+			return
+		}
+		if err != nil {
+			if _, ok := err.(respError); !ok {
+				err = respError("ERR " + err.Error())
+			}
+			reply = err
+		}
+		writeRESP(w, reply)
+
+		// Pipelined commands are answered together
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Limits of the commands read by readRESPCommand, as in Redis, so that a client cannot make the server allocate
+// unbounded memory from the lengths it sends or from a line that never ends.
+const (
+	respMaxLineLen      = 64 * 1024
+	respMaxMultibulkLen = 1024 * 1024
+	respMaxBulkLen      = 512 * 1024 * 1024
+)
+
+// readRESPCommand reads a command sent as a RESP array of bulk strings, or as an inline command.
+func readRESPCommand(r *bufio.Reader) ([][]byte, error) {
+	line, err := readRESPLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		fields := strings.Fields(string(line))
+		args := make([][]byte, len(fields))
+		for i, field := range fields {
+			args[i] = []byte(field)
+		}
+		return args, nil
+	}
+
+	n, err := strconv.Atoi(string(line[1:]))
+	if err != nil || n < 0 || n > respMaxMultibulkLen {
+		return nil, respError("ERR Protocol error: invalid multibulk length")
+	}
+	// Arguments are appended as they arrive so that a large count alone does not allocate
+	args := make([][]byte, 0, minInt(n, 64))
+	for i := 0; i < n; i++ {
+		line, err := readRESPLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, respError("ERR Protocol error: expected '$'")
+		}
+		size, err := strconv.Atoi(string(line[1:]))
+		if err != nil || size < 0 || size > respMaxBulkLen {
+			return nil, respError("ERR Protocol error: invalid bulk length")
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, buf[:size])
+	}
+	return args, nil
+}
+
+// readRESPLine reads an inline command or the header line of an array or bulk string, up to respMaxLineLen bytes.
+func readRESPLine(r *bufio.Reader) ([]byte, error) {
+	line, err := readLine(r, respMaxLineLen)
+	if err == errLineTooLong {
+		return nil, respError("ERR Protocol error: too big inline request")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return line, nil
+}
+
+// writeRESP writes a reply. Strings are simple strings, []byte are bulk strings, nil is a null bulk string and
+// []interface{} is an array.
+func writeRESP(w *bufio.Writer, reply interface{}) {
+	switch v := reply.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case string:
+		fmt.Fprintf(w, "+%v\r\n", v)
+	case respError:
+		fmt.Fprintf(w, "-%v\r\n", v)
+	case int:
+		fmt.Fprintf(w, ":%v\r\n", v)
+	case int64:
+		fmt.Fprintf(w, ":%v\r\n", v)
+	case []byte:
+		if v == nil {
+			w.WriteString("$-1\r\n")
+			return
+		}
+		fmt.Fprintf(w, "$%v\r\n", len(v))
+		w.Write(v)
+		w.WriteString("\r\n")
+	case []interface{}:
+		fmt.Fprintf(w, "*%v\r\n", len(v))
+		for _, item := range v {
+			writeRESP(w, item)
+		}
+	}
+}
+
+func (s *RESPServer) exec(cmd string, args [][]byte) (interface{}, error) {
+	switch cmd {
+	case "PING":
+		if len(args) > 0 {
+			return args[0], nil
+		}
+		return "PONG", nil
+	case "ECHO":
+		if len(args) != 1 {
+			return nil, wrongArgs(cmd)
+		}
+		return args[0], nil
+	case "QUIT":
+		return nil, errRESPQuit
+	case "SELECT":
+		if len(args) != 1 {
+			return nil, wrongArgs(cmd)
+		}
+		if string(args[0]) != "0" {
+			return nil, respError("ERR DB index is out of range")
+		}
+		return "OK", nil
+	case "COMMAND":
+		return []interface{}{}, nil
+	case "GET":
+		if len(args) != 1 {
+			return nil, wrongArgs(cmd)
+		}
+		value, err := Get(s.db, args[0])
+		if err == badger.ErrKeyNotFound {
+			return nil, nil
+		}
+		return value, err
+	case "SET":
+		return s.set(args)
+	case "DEL":
+		if len(args) < 1 {
+			return nil, wrongArgs(cmd)
+		}
+		return s.del(args)
+	case "EXISTS":
+		if len(args) < 1 {
+			return nil, wrongArgs(cmd)
+		}
+		return s.exists(args)
+	case "MGET":
+		if len(args) < 1 {
+			return nil, wrongArgs(cmd)
+		}
+		return s.mget(args)
+	case "MSET":
+		if len(args) == 0 || len(args)%2 != 0 {
+			return nil, wrongArgs(cmd)
+		}
+		return s.mset(args)
+	case "INCR", "DECR":
+		if len(args) != 1 {
+			return nil, wrongArgs(cmd)
+		}
+		delta := int64(1)
+		if cmd == "DECR" {
+			delta = -1
+		}
+		return s.incrBy(args[0], delta)
+	case "INCRBY":
+		if len(args) != 2 {
+			return nil, wrongArgs(cmd)
+		}
+		delta, err := strconv.ParseInt(string(args[1]), 10, 64)
+		if err != nil {
+			return nil, errRESPNotInteger
+		}
+		return s.incrBy(args[0], delta)
+	case "DBSIZE":
+		return Count(s.db, nil)
+	case "SCAN":
+		if len(args) < 1 {
+			return nil, wrongArgs(cmd)
+		}
+		return s.scan(args)
+	default:
+		return nil, respError(fmt.Sprintf("ERR unknown command '%v'", strings.ToLower(cmd)))
+	}
+}
+
+func (s *RESPServer) set(args [][]byte) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, wrongArgs("SET")
+	}
+	key, value := args[0], args[1]
+	var ttl time.Duration
+	nx, xx := false, false
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(string(args[i])) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "EX", "PX":
+			if i+1 == len(args) {
+				return nil, errRESPSyntax
+			}
+			n, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil || n <= 0 {
+				return nil, respError("ERR invalid expire time in 'set' command")
+			}
+			unit := time.Second
+			if strings.ToUpper(string(args[i])) == "PX" {
+				unit = time.Millisecond
+			}
+			ttl = time.Duration(n) * unit
+			i++
+		default:
+			return nil, errRESPSyntax
+		}
+	}
+	if nx && xx {
+		return nil, errRESPSyntax
+	}
+
+	written := true
+	err := s.db.Update(func(txn *badger.Txn) error {
+		if nx || xx {
+			_, err := txn.Get(key)
+			if err != nil && err != badger.ErrKeyNotFound {
+				return err
+			}
+			if exists := err == nil; (nx && exists) || (xx && !exists) {
+				written = false
+				return nil
+			}
+		}
+		if ttl > 0 {
+			return txn.SetWithTTL(key, value, ttl)
+		}
+		return txn.Set(key, value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !written {
+		return nil, nil
+	}
+	return "OK", nil
+}
+
+func (s *RESPServer) del(keys [][]byte) (interface{}, error) {
+	deleted := 0
+	err := s.db.Update(func(txn *badger.Txn) error {
+		deleted = 0
+		for _, key := range keys {
+			if _, err := txn.Get(key); err == badger.ErrKeyNotFound {
+				continue
+			} else if err != nil {
+				return err
+			}
+			if err := txn.Delete(key); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+func (s *RESPServer) exists(keys [][]byte) (interface{}, error) {
+	count := 0
+	err := s.db.View(func(txn *badger.Txn) error {
+		for _, key := range keys {
+			if _, err := txn.Get(key); err == badger.ErrKeyNotFound {
+				continue
+			} else if err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (s *RESPServer) mget(keys [][]byte) (interface{}, error) {
+	values := make([]interface{}, len(keys))
+	err := s.db.View(func(txn *badger.Txn) error {
+		for i, key := range keys {
+			item, err := txn.Get(key)
+			if err == badger.ErrKeyNotFound {
+				continue
+			} else if err != nil {
+				return err
+			}
+			if values[i], err = item.ValueCopy(nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return values, err
+}
+
+func (s *RESPServer) mset(args [][]byte) (interface{}, error) {
+	err := s.db.Update(func(txn *badger.Txn) error {
+		for i := 0; i < len(args); i += 2 {
+			if err := txn.Set(args[i], args[i+1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return "OK", nil
+}
+
+// incrBy adds delta to the integer value of key with a read-modify-write transaction that is retried on conflict.
+func (s *RESPServer) incrBy(key []byte, delta int64) (interface{}, error) {
+	for {
+		var n int64
+		err := s.db.Update(func(txn *badger.Txn) error {
+			n = 0
+			item, err := txn.Get(key)
+			if err != nil && err != badger.ErrKeyNotFound {
+				return err
+			}
+			if err == nil {
+				value, err := item.Value()
+				if err != nil {
+					return err
+				}
+				if n, err = strconv.ParseInt(string(value), 10, 64); err != nil {
+					return errRESPNotInteger
+				}
+			}
+			if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+				return errRESPOverflow
+			}
+			n += delta
+			return txn.Set(key, []byte(strconv.FormatInt(n, 10)))
+		})
+		if err == badger.ErrConflict {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+}
+
+// scan implements SCAN. The cursor is the next key to read encoded as a decimal integer, since most clients
+// expect integer cursors, with 0 as the start and end of the iteration.
+func (s *RESPServer) scan(args [][]byte) (interface{}, error) {
+	start, err := decodeRESPCursor(string(args[0]))
+	if err != nil {
+		return nil, respError("ERR invalid cursor")
+	}
+	var pattern []byte
+	count := 10
+	for i := 1; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			return nil, errRESPSyntax
+		}
+		switch strings.ToUpper(string(args[i])) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			if count, err = strconv.Atoi(string(args[i+1])); err != nil || count < 1 {
+				return nil, errRESPSyntax
+			}
+		default:
+			return nil, errRESPSyntax
+		}
+	}
+
+	keys := make([]interface{}, 0)
+	opts := ScanOptions{Prefix: globPrefix(pattern), Start: start, Limit: count, KeysOnly: true}
+	next, err := Scan(s.db, opts, func(kv *KeyValue) error {
+		if pattern == nil || globMatch(pattern, kv.Key) {
+			keys = append(keys, kv.Key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{[]byte(encodeRESPCursor(next)), keys}, nil
+}
+
+// encodeRESPCursor encodes a key as the decimal integer of 0x01 followed by the key, so leading zero bytes are kept.
+func encodeRESPCursor(key []byte) string {
+	if key == nil {
+		return "0"
+	}
+	return new(big.Int).SetBytes(append([]byte{1}, key...)).String()
+}
+
+func decodeRESPCursor(cursor string) ([]byte, error) {
+	if cursor == "0" {
+		return nil, nil
+	}
+	n, ok := new(big.Int).SetString(cursor, 10)
+	if !ok || n.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid cursor %v", cursor)
+	}
+	return n.Bytes()[1:], nil
+}
+
+// globPrefix returns the literal prefix of a glob pattern.
+func globPrefix(pattern []byte) []byte {
+	for i, c := range pattern {
+		if c == '*' || c == '?' || c == '[' || c == '\\' {
+			return pattern[:i]
+		}
+	}
+	return pattern
+}
+
+// globMatch reports whether s matches a Redis glob pattern with *, ?, [...] classes and \ escapes. Every token
+// other than * matches one byte, so only the last * needs to be backtracked: on a mismatch it is made to match one
+// more byte, which keeps the match linear in the length of s for each star.
+func globMatch(pattern, s []byte) bool {
+	p, i := 0, 0
+	star, mark := -1, 0
+	for i < len(s) {
+		if p < len(pattern) && pattern[p] == '*' {
+			star, mark = p, i
+			p++
+			continue
+		}
+		if p < len(pattern) {
+			if width, ok := globMatchByte(pattern[p:], s[i]); ok {
+				p += width
+				i++
+				continue
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		mark++
+		p, i = star+1, mark
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// globMatchByte reports whether c matches the first token of pattern, which is not *, and returns the width of the
+// token.
+func globMatchByte(pattern []byte, c byte) (int, bool) {
+	switch pattern[0] {
+	case '?':
+		return 1, true
+	case '[':
+		end := bytes.IndexByte(pattern[1:], ']')
+		if end < 0 {
+			// An unterminated class matches a literal '['
+			return 1, c == '['
+		}
+		class := pattern[1 : end+1]
+		negate := len(class) > 0 && class[0] == '^'
+		if negate {
+			class = class[1:]
+		}
+		return end + 2, classMatch(class, c) != negate
+	case '\\':
+		if len(pattern) > 1 {
+			return 2, pattern[1] == c
+		}
+	}
+	return 1, pattern[0] == c
+}
+
+func classMatch(class []byte, c byte) bool {
+	for i := 0; i < len(class); i++ {
+		if i+2 < len(class) && class[i+1] == '-' {
+			if class[i] <= c && c <= class[i+2] {
+				return true
+			}
+			i += 2
+			continue
+		}
+		if class[i] == c {
+			return true
+		}
+	}
+	return false
+}
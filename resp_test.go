@@ -0,0 +1,187 @@
+package badgerutils
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readRESPReply reads a reply and renders it as a string for comparison.
+func readRESPReply(t *testing.T, r *bufio.Reader) string {
+	line, err := readRESPLine(r)
+	require.Nil(t, err)
+	switch line[0] {
+	case '$':
+		size, err := strconv.Atoi(string(line[1:]))
+		require.Nil(t, err)
+		if size < 0 {
+			return "(nil)"
+		}
+		value, err := readRESPLine(r)
+		require.Nil(t, err)
+		return string(value)
+	case '*':
+		n, err := strconv.Atoi(string(line[1:]))
+		require.Nil(t, err)
+		items := make([]string, n)
+		for i := range items {
+			items[i] = readRESPReply(t, r)
+		}
+		return "[" + strings.Join(items, " ") + "]"
+	default:
+		return string(line)
+	}
+}
+
+func TestRESPServer(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(path.Join(tmpDir, "db"))
+	require.Nil(t, err)
+	defer db.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+
+	server := NewRESPServer(db)
+	go server.Serve(l)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.Nil(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	do := func(args ...string) string {
+		fmt.Fprintf(conn, "*%v\r\n", len(args))
+		for _, arg := range args {
+			fmt.Fprintf(conn, "$%v\r\n%v\r\n", len(arg), arg)
+		}
+		return readRESPReply(t, r)
+	}
+
+	require.Equal(t, "+PONG", do("PING"))
+	require.Equal(t, "+OK", do("SET", "spot:1", "pipeline"))
+	require.Equal(t, "pipeline", do("GET", "spot:1"))
+	require.Equal(t, "(nil)", do("GET", "missing"))
+	require.Equal(t, "(nil)", do("SET", "spot:1", "other", "NX"))
+	require.Equal(t, "+OK", do("SET", "spot:2", "mavericks", "EX", "60"))
+	require.Equal(t, "+OK", do("MSET", "buoy:1", "a", "buoy:2", "b"))
+	require.Equal(t, "[pipeline (nil) a]", do("MGET", "spot:1", "spot:3", "buoy:1"))
+	require.Equal(t, ":2", do("EXISTS", "spot:1", "spot:2", "spot:3"))
+	require.Equal(t, ":4", do("DBSIZE"))
+	require.Equal(t, ":1", do("DEL", "buoy:2", "buoy:3"))
+	require.Equal(t, ":1", do("INCR", "counter"))
+	require.Equal(t, ":11", do("INCRBY", "counter", "10"))
+	require.True(t, strings.HasPrefix(do("INCR", "spot:1"), "-ERR value is not an integer"))
+	require.Equal(t, "+OK", do("SET", "max", "9223372036854775807"))
+	require.Equal(t, "-ERR increment or decrement would overflow", do("INCR", "max"))
+	require.Equal(t, ":9223372036854775806", do("DECR", "max"))
+	require.Equal(t, "+OK", do("SET", "min", "-9223372036854775808"))
+	require.Equal(t, "-ERR increment or decrement would overflow", do("DECR", "min"))
+	require.Equal(t, "-ERR increment or decrement would overflow", do("INCRBY", "min", "-1"))
+	require.True(t, strings.HasPrefix(do("NOPE"), "-ERR unknown command"))
+
+	// Page through the keyspace with a match pattern
+	keys := make([]string, 0)
+	cursor := "0"
+	for {
+		reply := do("SCAN", cursor, "MATCH", "spot:*", "COUNT", "1")
+		fields := strings.Fields(strings.NewReplacer("[", "", "]", "").Replace(reply))
+		cursor = fields[0]
+		keys = append(keys, fields[1:]...)
+		if cursor == "0" {
+			break
+		}
+	}
+	require.Equal(t, []string{"spot:1", "spot:2"}, keys)
+
+	// Inline commands are supported
+	fmt.Fprintf(conn, "GET counter\r\n")
+	require.Equal(t, "11", readRESPReply(t, r))
+
+	require.Equal(t, "+OK", do("QUIT"))
+
+	// Protocol errors are replied to before the connection is closed
+	conn, err = net.Dial("tcp", l.Addr().String())
+	require.Nil(t, err)
+	defer conn.Close()
+	fmt.Fprintf(conn, "*999999999999\r\n")
+	require.Equal(t, "-ERR Protocol error: invalid multibulk length", readRESPReply(t, bufio.NewReader(conn)))
+}
+
+func TestReadRESPCommand(t *testing.T) {
+	read := func(input string) ([][]byte, error) {
+		return readRESPCommand(bufio.NewReader(strings.NewReader(input)))
+	}
+
+	args, err := read("*2\r\n$3\r\nGET\r\n$6\r\nspot:1\r\n")
+	require.Nil(t, err)
+	require.Equal(t, [][]byte{[]byte("GET"), []byte("spot:1")}, args)
+
+	// Lengths beyond the limits are rejected before anything is allocated
+	for _, input := range []string{"*999999999999\r\n", "*-1\r\n", fmt.Sprintf("*%v\r\n", respMaxMultibulkLen+1)} {
+		_, err = read(input)
+		require.Equal(t, respError("ERR Protocol error: invalid multibulk length"), err, input)
+	}
+	for _, input := range []string{"*1\r\n$9999999999\r\n", "*1\r\n$-5\r\n", fmt.Sprintf("*1\r\n$%v\r\n", respMaxBulkLen+1)} {
+		_, err = read(input)
+		require.Equal(t, respError("ERR Protocol error: invalid bulk length"), err, input)
+	}
+
+	// A large count without the arguments fails when the connection ends
+	_, err = read("*1000000\r\n$1\r\na\r\n")
+	require.NotNil(t, err)
+
+	// Lines are bounded whether or not they end
+	long := strings.Repeat("a", respMaxLineLen+1)
+	for _, input := range []string{"GET " + long, "GET " + long + "\r\n", "*1\r\n$" + long + "\r\n"} {
+		_, err = read(input)
+		require.Equal(t, respError("ERR Protocol error: too big inline request"), err)
+	}
+	args, err = read("GET " + strings.Repeat("a", respMaxLineLen-10) + "\r\n")
+	require.Nil(t, err)
+	require.Equal(t, respMaxLineLen-10, len(args[1]))
+}
+
+func TestGlobMatch(t *testing.T) {
+	require.True(t, globMatch([]byte("spot:*"), []byte("spot:1")))
+	require.True(t, globMatch([]byte("*:1"), []byte("spot:1")))
+	require.True(t, globMatch([]byte("spot:?"), []byte("spot:1")))
+	require.False(t, globMatch([]byte("spot:?"), []byte("spot:12")))
+	require.True(t, globMatch([]byte("spot:[0-9]"), []byte("spot:7")))
+	require.False(t, globMatch([]byte("spot:[^0-9]"), []byte("spot:7")))
+	require.True(t, globMatch([]byte(`spot\*`), []byte("spot*")))
+	require.False(t, globMatch([]byte(`spot\*`), []byte("spot1")))
+	require.True(t, globMatch([]byte("*spot*:*1"), []byte("a:spot:b:21")))
+	require.False(t, globMatch([]byte("*spot*:*1"), []byte("a:spot:b:12")))
+	require.True(t, globMatch([]byte("[spot"), []byte("[spot")))
+	require.True(t, globMatch([]byte("**"), []byte("")))
+	require.False(t, globMatch([]byte("?"), []byte("")))
+
+	// Patterns with many stars that fail to match take linear time per star instead of backtracking exponentially
+	pattern := []byte(strings.Repeat("*a", 30) + "b")
+	start := time.Now()
+	require.False(t, globMatch(pattern, bytes.Repeat([]byte("a"), 100)))
+	require.True(t, time.Since(start) < time.Second)
+	require.Equal(t, []byte("spot:"), globPrefix([]byte("spot:*")))
+
+	cursor := encodeRESPCursor([]byte{0, 1})
+	key, err := decodeRESPCursor(cursor)
+	require.Nil(t, err)
+	require.Equal(t, []byte{0, 1}, key)
+}
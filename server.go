@@ -0,0 +1,212 @@
+package badgerutils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/badger"
+)
+
+// HandlerOptions configures the HTTP API returned by NewHandler.
+type HandlerOptions struct {
+	// AllowWrites enables the POST /batch endpoint.
+	AllowWrites bool
+	// DefaultLimit is the page size of /scan when no limit is given. Defaults to 100.
+	DefaultLimit int
+	// MaxLimit is the largest page size of /scan. Defaults to 1000.
+	MaxLimit int
+	// Stats configures the prefixes reported by /stats.
+	Stats StatsOptions
+	// Metrics is served at GET /metrics when set, usually a *PrometheusMetrics.
+	Metrics http.Handler
+}
+
+// BatchOperation is a single write of a POST /batch request. Keys and values are base64 encoded in JSON.
+type BatchOperation struct {
+	Key    []byte `json:"key"`
+	Value  []byte `json:"value,omitempty"`
+	Delete bool   `json:"delete,omitempty"`
+}
+
+// ScanPage is the response of GET /scan. Cursor is passed as the cursor parameter to read the next page and is
+// empty on the last page.
+type ScanPage struct {
+	Items  []KeyValue `json:"items"`
+	Cursor string     `json:"cursor,omitempty"`
+}
+
+type handler struct {
+	db   *badger.DB
+	opts HandlerOptions
+}
+
+// NewHandler returns an HTTP/JSON API over db with the following endpoints:
+//
+//	GET /keys/{key}                                  returns the key/value, or the raw value with ?raw=true
+//	GET /scan?prefix=&start=&limit=&cursor=          returns a ScanPage with the same semantics as Scan
+//	GET /stats                                       returns DBStats
+//	POST /batch                                      applies a JSON array of BatchOperations when AllowWrites is set
+//	GET /metrics                                     serves Metrics when set
+//
+// Keys and values are base64 encoded in JSON responses.
+func NewHandler(db *badger.DB, opts HandlerOptions) http.Handler {
+	if opts.DefaultLimit <= 0 {
+		opts.DefaultLimit = 100
+	}
+	if opts.MaxLimit <= 0 {
+		opts.MaxLimit = 1000
+	}
+
+	h := &handler{db: db, opts: opts}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/keys/", h.getKey)
+	mux.HandleFunc("/scan", h.scan)
+	mux.HandleFunc("/stats", h.stats)
+	mux.HandleFunc("/batch", h.batch)
+	if opts.Metrics != nil {
+		mux.Handle("/metrics", opts.Metrics)
+	}
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func requireMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+		return false
+	}
+	return true
+}
+
+func (h *handler) getKey(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	key := []byte(strings.TrimPrefix(r.URL.Path, "/keys/"))
+	value, err := Get(h.db, key)
+	if err == badger.ErrKeyNotFound {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if raw, _ := strconv.ParseBool(r.URL.Query().Get("raw")); raw {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(value)
+		return
+	}
+	writeJSON(w, http.StatusOK, KeyValue{Key: key, Value: value})
+}
+
+func (h *handler) scan(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	query := r.URL.Query()
+	opts := ScanOptions{
+		Prefix: []byte(query.Get("prefix")),
+		Start:  []byte(query.Get("start")),
+		Limit:  h.opts.DefaultLimit,
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", limit))
+			return
+		}
+		opts.Limit = n
+	}
+	if opts.Limit > h.opts.MaxLimit {
+		opts.Limit = h.opts.MaxLimit
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		start, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid cursor %q", cursor))
+			return
+		}
+		opts.Start = start
+	}
+
+	page := ScanPage{Items: make([]KeyValue, 0)}
+	next, err := Scan(h.db, opts, func(kv *KeyValue) error {
+		page.Items = append(page.Items, *kv)
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if next != nil {
+		page.Cursor = base64.RawURLEncoding.EncodeToString(next)
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *handler) stats(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	stats, err := collectStats(h.db, h.opts.Stats)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (h *handler) batch(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	if !h.opts.AllowWrites {
+		writeError(w, http.StatusForbidden, fmt.Errorf("writes are not enabled"))
+		return
+	}
+
+	ops := make([]BatchOperation, 0)
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err := h.db.Update(func(txn *badger.Txn) error {
+		for _, op := range ops {
+			if op.Delete {
+				if err := txn.Delete(op.Key); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := txn.Set(op.Key, op.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"applied": len(ops)})
+}
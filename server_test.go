@@ -0,0 +1,101 @@
+package badgerutils
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(path.Join(tmpDir, "db"))
+	require.Nil(t, err)
+	defer db.Close()
+
+	for _, key := range []string{"spot/1", "spot/2", "spot/3", "buoy/1"} {
+		require.Nil(t, Put(db, []byte(key), []byte("value-"+key)))
+	}
+
+	readOnly := httptest.NewServer(NewHandler(db, HandlerOptions{DefaultLimit: 2}))
+	defer readOnly.Close()
+
+	resp, err := http.Get(readOnly.URL + "/keys/spot/2")
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	kv := KeyValue{}
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(&kv))
+	resp.Body.Close()
+	require.Equal(t, KeyValue{Key: []byte("spot/2"), Value: []byte("value-spot/2")}, kv)
+
+	resp, err = http.Get(readOnly.URL + "/keys/spot/2?raw=true")
+	require.Nil(t, err)
+	raw, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Nil(t, err)
+	require.Equal(t, "value-spot/2", string(raw))
+
+	resp, err = http.Get(readOnly.URL + "/keys/missing")
+	require.Nil(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Page through the prefix with the cursor
+	keys := make([]string, 0)
+	cursor := ""
+	for {
+		resp, err = http.Get(readOnly.URL + "/scan?prefix=spot/&cursor=" + url.QueryEscape(cursor))
+		require.Nil(t, err)
+		page := ScanPage{}
+		require.Nil(t, json.NewDecoder(resp.Body).Decode(&page))
+		resp.Body.Close()
+		for _, item := range page.Items {
+			keys = append(keys, string(item.Key))
+		}
+		if page.Cursor == "" {
+			break
+		}
+		cursor = page.Cursor
+	}
+	require.Equal(t, []string{"spot/1", "spot/2", "spot/3"}, keys)
+
+	resp, err = http.Get(readOnly.URL + "/stats")
+	require.Nil(t, err)
+	stats := DBStats{}
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(&stats))
+	resp.Body.Close()
+	require.Equal(t, 4, stats.LiveKeys)
+
+	batch := `[{"key":"YnVveS8y","value":"dmFsdWU="},{"key":"YnVveS8x","delete":true}]`
+	resp, err = http.Post(readOnly.URL+"/batch", "application/json", strings.NewReader(batch))
+	require.Nil(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	writable := httptest.NewServer(NewHandler(db, HandlerOptions{AllowWrites: true}))
+	defer writable.Close()
+
+	resp, err = http.Post(writable.URL+"/batch", "application/json", strings.NewReader(batch))
+	require.Nil(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	count, err := Count(db, []byte("buoy/"))
+	require.Nil(t, err)
+	require.Equal(t, 1, count)
+	value, err := Get(db, []byte("buoy/2"))
+	require.Nil(t, err)
+	require.Equal(t, []byte("value"), value)
+}
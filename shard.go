@@ -0,0 +1,367 @@
+package badgerutils
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dgraph-io/badger"
+)
+
+// Router picks the shard of a key.
+type Router interface {
+	// Shards is the number of shards keys are spread over.
+	Shards() int
+	// Shard returns the shard of key, from 0 to Shards()-1.
+	Shard(key []byte) int
+}
+
+// HashRouter spreads keys over shards with a consistent hash ring, so that changing the number of shards moves
+// about as few keys as possible.
+type HashRouter struct {
+	shards int
+	points []uint64
+	owners []int
+}
+
+// hashRouterReplicas is the number of points of each shard on the ring
+const hashRouterReplicas = 128
+
+// hashKey hashes key with FNV-1a, mixed with the MurmurHash3 finalizer because FNV alone spreads keys that only
+// differ in their last bytes poorly over the ring.
+func hashKey(key []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(key)
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// NewHashRouter creates a HashRouter over n shards. It panics when n is less than 1.
+func NewHashRouter(n int) *HashRouter {
+	if n < 1 {
+		panic(fmt.Sprintf("badgerutils: NewHashRouter needs at least 1 shard, got %v", n))
+	}
+	type point struct {
+		hash  uint64
+		shard int
+	}
+	points := make([]point, 0, n*hashRouterReplicas)
+	for shard := 0; shard < n; shard++ {
+		for replica := 0; replica < hashRouterReplicas; replica++ {
+			points = append(points, point{hashKey([]byte(fmt.Sprintf("shard-%d-%d", shard, replica))), shard})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].hash < points[j].hash })
+
+	r := &HashRouter{shards: n}
+	for _, p := range points {
+		r.points = append(r.points, p.hash)
+		r.owners = append(r.owners, p.shard)
+	}
+	return r
+}
+
+// Shards is the number of shards.
+func (r *HashRouter) Shards() int {
+	return r.shards
+}
+
+// Shard returns the shard owning the first point of the ring at or after the hash of key.
+func (r *HashRouter) Shard(key []byte) int {
+	h := hashKey(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[i]
+}
+
+// RangeRouter assigns contiguous key ranges to shards. Shard 0 holds the keys before Splits[0], shard i the keys
+// from Splits[i-1] up to Splits[i], and the last shard the keys from the last split.
+type RangeRouter struct {
+	Splits [][]byte
+}
+
+// NewRangeRouter creates a RangeRouter over len(splits)+1 shards. splits are sorted.
+func NewRangeRouter(splits [][]byte) *RangeRouter {
+	sorted := make([][]byte, len(splits))
+	copy(sorted, splits)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 })
+	return &RangeRouter{Splits: sorted}
+}
+
+// Shards is the number of shards.
+func (r *RangeRouter) Shards() int {
+	return len(r.Splits) + 1
+}
+
+// Shard returns the shard of the range containing key.
+func (r *RangeRouter) Shard(key []byte) int {
+	return sort.Search(len(r.Splits), func(i int) bool { return bytes.Compare(key, r.Splits[i]) < 0 })
+}
+
+// ShardDirs returns the directories of n shards under base.
+func ShardDirs(base string, n int) []string {
+	dirs := make([]string, n)
+	for i := range dirs {
+		dirs[i] = path.Join(base, fmt.Sprintf("shard-%03d", i))
+	}
+	return dirs
+}
+
+// ShardedDB spreads keys over several Badgers picked by a Router.
+type ShardedDB struct {
+	router Router
+	dbs    []*badger.DB
+}
+
+// OpenSharded opens the Badger of each shard in dirs with DefaultOptions. There must be one directory per shard
+// of router.
+func OpenSharded(dirs []string, router Router) (*ShardedDB, error) {
+	if router.Shards() < 1 {
+		return nil, fmt.Errorf("a sharded DB needs at least 1 shard")
+	}
+	if len(dirs) != router.Shards() {
+		return nil, fmt.Errorf("%v directories for %v shards", len(dirs), router.Shards())
+	}
+
+	s := &ShardedDB{router: router}
+	for _, dir := range dirs {
+		db, err := Open(dir)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.dbs = append(s.dbs, db)
+	}
+	return s, nil
+}
+
+// Close closes every shard.
+func (s *ShardedDB) Close() error {
+	var firstErr error
+	for _, db := range s.dbs {
+		if err := db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// DB returns the Badger of a shard.
+func (s *ShardedDB) DB(shard int) *badger.DB {
+	return s.dbs[shard]
+}
+
+func (s *ShardedDB) shardOf(key []byte) *badger.DB {
+	return s.dbs[s.router.Shard(key)]
+}
+
+// Get returns the value of key. It returns badger.ErrKeyNotFound when the key does not exist.
+func (s *ShardedDB) Get(key []byte) ([]byte, error) {
+	return Get(s.shardOf(key), key)
+}
+
+// Put sets the value of key.
+func (s *ShardedDB) Put(key, value []byte) error {
+	return Put(s.shardOf(key), key, value)
+}
+
+// Delete removes key.
+func (s *ShardedDB) Delete(key []byte) error {
+	return Delete(s.shardOf(key), key)
+}
+
+// Count returns the number of keys with prefix across all shards, counting the shards in parallel.
+func (s *ShardedDB) Count(prefix []byte) (int, error) {
+	counts := make([]int, len(s.dbs))
+	errs := make([]error, len(s.dbs))
+	var wg sync.WaitGroup
+	for i, db := range s.dbs {
+		wg.Add(1)
+		go func(i int, db *badger.DB) {
+			defer wg.Done()
+			counts[i], errs[i] = Count(db, prefix)
+		}(i, db)
+	}
+	wg.Wait()
+
+	total := 0
+	for i := range counts {
+		if errs[i] != nil {
+			return 0, errs[i]
+		}
+		total += counts[i]
+	}
+	return total, nil
+}
+
+// shardCursor is the position of a merged scan in one shard.
+type shardCursor struct {
+	txn *badger.Txn
+	it  *badger.Iterator
+}
+
+// cursorHeap orders shard cursors by their current key.
+type cursorHeap []*shardCursor
+
+func (h cursorHeap) Len() int { return len(h) }
+func (h cursorHeap) Less(i, j int) bool {
+	return bytes.Compare(h[i].it.Item().Key(), h[j].it.Item().Key()) < 0
+}
+func (h cursorHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *cursorHeap) Push(x interface{}) { *h = append(*h, x.(*shardCursor)) }
+func (h *cursorHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// Scan calls fn for each key/value of every shard in key order within the range defined by opts, with the same
+// paging as Scan.
+func (s *ShardedDB) Scan(opts ScanOptions, fn func(*KeyValue) error) ([]byte, error) {
+	start := opts.Start
+	if bytes.Compare(opts.Prefix, start) > 0 {
+		start = opts.Prefix
+	}
+
+	cursors := make(cursorHeap, 0, len(s.dbs))
+	defer func() {
+		for _, c := range cursors {
+			c.it.Close()
+			c.txn.Discard()
+		}
+	}()
+	for _, db := range s.dbs {
+		txn := db.NewTransaction(false)
+		iteratorOpts := badger.DefaultIteratorOptions
+		iteratorOpts.PrefetchValues = !opts.KeysOnly
+		it := txn.NewIterator(iteratorOpts)
+		it.Seek(start)
+		cursors = append(cursors, &shardCursor{txn: txn, it: it})
+	}
+
+	// live holds the cursors with keys left, while cursors keeps all of them to be closed
+	live := make(cursorHeap, 0, len(cursors))
+	for _, c := range cursors {
+		if opts.valid(c.it) {
+			live = append(live, c)
+		}
+	}
+	heap.Init(&live)
+
+	count := 0
+	for live.Len() > 0 {
+		c := live[0]
+		item := c.it.Item()
+		if opts.Limit > 0 && count == opts.Limit {
+			return item.KeyCopy(nil), nil
+		}
+		kv := &KeyValue{Key: item.KeyCopy(nil)}
+		if !opts.KeysOnly {
+			value, err := item.ValueCopy(nil)
+			if err != nil {
+				return nil, err
+			}
+			kv.Value = value
+		}
+		if err := fn(kv); err != nil {
+			return nil, err
+		}
+		count++
+
+		c.it.Next()
+		if opts.valid(c.it) {
+			heap.Fix(&live, 0)
+		} else {
+			heap.Pop(&live)
+		}
+	}
+	return nil, nil
+}
+
+// Iterate calls fn for each key/value with prefix in key order across all shards, so a ShardedDB is a Source.
+func (s *ShardedDB) Iterate(prefix []byte, fn func(*KeyValue) error) error {
+	_, err := s.Scan(ScanOptions{Prefix: prefix}, fn)
+	return err
+}
+
+// WriteBatch splits kvs by shard and commits each part to its shard in parallel, so a ShardedDB is a Sink.
+func (s *ShardedDB) WriteBatch(kvs []KeyValue, done func(int32, error)) {
+	parts := make([][]KeyValue, len(s.dbs))
+	for _, kv := range kvs {
+		shard := s.router.Shard(kv.Key)
+		parts[shard] = append(parts[shard], kv)
+	}
+
+	var mu sync.Mutex
+	var written int32
+	errs := make([]string, 0)
+	var wg sync.WaitGroup
+	for shard, part := range parts {
+		if len(part) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go commitBatch(s.dbs[shard], part, ConflictOverwrite, func(n int32, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			written += n
+			if err != nil {
+				errs = append(errs, err.Error())
+			}
+			wg.Done()
+		})
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		done(written, fmt.Errorf("%v", strings.Join(errs, "; ")))
+		return
+	}
+	done(written, nil)
+}
+
+// WriteStream translates the lines of reader into key/values and writes them into their shards, committing
+// batches to the shards in parallel.
+func (s *ShardedDB) WriteStream(reader io.Reader, batchSize int, lineToKeyValue func(string) (*KeyValue, error)) error {
+	_, err := WriteStreamTo(context.Background(), reader, s, batchSize, lineToKeyValue, nil)
+	return err
+}
+
+// Reshard copies every key/value of the Badgers in srcDirs into the shards in dstDirs picked by router. It is run
+// offline: the source shards must not be written while resharding. The source shards are left untouched.
+func Reshard(srcDirs, dstDirs []string, router Router, batchSize int) error {
+	dst, err := OpenSharded(dstDirs, router)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	for _, dir := range srcDirs {
+		db, err := openDB(dir)
+		if err != nil {
+			return err
+		}
+		result, err := FromSource(&BadgerSource{DB: db}, nil).BatchSize(batchSize).To(dst)
+		db.Close()
+		if err != nil {
+			return fmt.Errorf("%v: %v", dir, err)
+		}
+		DefaultLogger.Log(LevelInfo, "Resharded", "dir", dir, "records", result.Records)
+	}
+	return nil
+}
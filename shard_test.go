@@ -0,0 +1,138 @@
+package badgerutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/dgraph-io/badger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashRouter(t *testing.T) {
+	r4 := NewHashRouter(4)
+	r5 := NewHashRouter(5)
+	require.Equal(t, 4, r4.Shards())
+
+	counts := make([]int, 4)
+	moved := 0
+	for i := 0; i < 10000; i++ {
+		key := []byte(fmt.Sprintf("key%v", i))
+		shard := r4.Shard(key)
+		require.Equal(t, shard, r4.Shard(key))
+		counts[shard]++
+		if r5.Shard(key) != shard {
+			moved++
+		}
+	}
+	for _, count := range counts {
+		require.InDelta(t, 2500, count, 750)
+	}
+	// Adding a fifth shard moves about a fifth of the keys
+	require.InDelta(t, 2000, moved, 750)
+}
+
+func TestRangeRouter(t *testing.T) {
+	r := NewRangeRouter([][]byte{[]byte("m"), []byte("f")})
+	require.Equal(t, 3, r.Shards())
+	require.Equal(t, 0, r.Shard([]byte("a")))
+	require.Equal(t, 1, r.Shard([]byte("f")))
+	require.Equal(t, 1, r.Shard([]byte("l")))
+	require.Equal(t, 2, r.Shard([]byte("m")))
+	require.Equal(t, 2, r.Shard([]byte("z")))
+}
+
+// zeroRouter is a misconfigured router without shards.
+type zeroRouter struct{}
+
+func (zeroRouter) Shards() int      { return 0 }
+func (zeroRouter) Shard([]byte) int { return 0 }
+
+func TestShardedDB(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	lines := make([]string, 0)
+	for i := 0; i < 100; i++ {
+		lines = append(lines, fmt.Sprintf("key%03d:value%v", i, i))
+	}
+
+	dirs := ShardDirs(path.Join(tmpDir, "sharded"), 3)
+	_, err = OpenSharded(dirs, NewHashRouter(4))
+	require.NotNil(t, err)
+	require.Panics(t, func() { NewHashRouter(0) })
+	_, err = OpenSharded(nil, zeroRouter{})
+	require.NotNil(t, err)
+
+	s, err := OpenSharded(dirs, NewHashRouter(3))
+	require.Nil(t, err)
+	require.Nil(t, s.WriteStream(strings.NewReader(strings.Join(lines, "\n")), 10, csvToKeyValue))
+
+	for i := 0; i < 3; i++ {
+		count, err := Count(s.DB(i), nil)
+		require.Nil(t, err)
+		require.True(t, count > 0 && count < 100)
+	}
+
+	value, err := s.Get([]byte("key042"))
+	require.Nil(t, err)
+	require.Equal(t, "value42", string(value))
+	require.Nil(t, s.Put([]byte("key100"), []byte("value100")))
+	require.Nil(t, s.Delete([]byte("key000")))
+	_, err = s.Get([]byte("key000"))
+	require.Equal(t, badger.ErrKeyNotFound, err)
+
+	count, err := s.Count([]byte("key"))
+	require.Nil(t, err)
+	require.Equal(t, 100, count)
+
+	// Scans merge the shards in key order and page with the key after the limit
+	keys := make([]string, 0)
+	next, err := s.Scan(ScanOptions{Prefix: []byte("key0"), Start: []byte("key095"), Limit: 3}, func(kv *KeyValue) error {
+		keys = append(keys, string(kv.Key))
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, []string{"key095", "key096", "key097"}, keys)
+	require.Equal(t, "key098", string(next))
+
+	keys = keys[:0]
+	next, err = s.Scan(ScanOptions{Start: []byte("key095"), End: []byte("key098")}, func(kv *KeyValue) error {
+		keys = append(keys, string(kv.Key))
+		return nil
+	})
+	require.Nil(t, err)
+	require.Nil(t, next)
+	require.Equal(t, []string{"key095", "key096", "key097"}, keys)
+
+	keys = keys[:0]
+	require.Nil(t, s.Iterate(nil, func(kv *KeyValue) error {
+		keys = append(keys, string(kv.Key))
+		return nil
+	}))
+	require.Equal(t, 100, len(keys))
+	require.Equal(t, "key001", keys[0])
+	require.Equal(t, "key100", keys[99])
+	require.Nil(t, s.Close())
+
+	// Reshard from 3 hash shards to 2 range shards
+	dstDirs := ShardDirs(path.Join(tmpDir, "resharded"), 2)
+	router := NewRangeRouter([][]byte{[]byte("key050")})
+	require.Nil(t, Reshard(dirs, dstDirs, router, 7))
+
+	resharded, err := OpenSharded(dstDirs, router)
+	require.Nil(t, err)
+	defer resharded.Close()
+	count, err = Count(resharded.DB(0), nil)
+	require.Nil(t, err)
+	require.Equal(t, 49, count)
+	count, err = Count(resharded.DB(1), nil)
+	require.Nil(t, err)
+	require.Equal(t, 51, count)
+}
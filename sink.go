@@ -0,0 +1,135 @@
+package badgerutils
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/dgraph-io/badger"
+)
+
+// Sink writes batches of key/values.
+type Sink interface {
+	// WriteBatch writes kvs and calls done with the number of key/values written once the batch is committed or
+	// has failed. It may be called from several goroutines at once.
+	WriteBatch(kvs []KeyValue, done func(int32, error))
+}
+
+// Source reads key/values in key order.
+type Source interface {
+	// Iterate calls fn for each key/value with prefix in key order, and stops at the first error returned by fn.
+	Iterate(prefix []byte, fn func(*KeyValue) error) error
+}
+
+// BadgerSink writes each batch into a Badger in a single transaction.
+type BadgerSink struct {
+	DB *badger.DB
+	// Conflict defines how keys that already exist are handled.
+	Conflict ConflictPolicy
+}
+
+// NewBadgerSink creates a BadgerSink that overwrites existing keys of db.
+func NewBadgerSink(db *badger.DB) *BadgerSink {
+	return &BadgerSink{DB: db}
+}
+
+// WriteBatch commits kvs in a single transaction.
+func (s *BadgerSink) WriteBatch(kvs []KeyValue, done func(int32, error)) {
+	commitBatch(s.DB, kvs, s.Conflict, done)
+}
+
+// BadgerSource reads the key/values of a Badger.
+type BadgerSource struct {
+	DB *badger.DB
+}
+
+// Iterate calls fn for each key/value with prefix from a single snapshot of the Badger.
+func (s *BadgerSource) Iterate(prefix []byte, fn func(*KeyValue) error) error {
+	_, err := Scan(s.DB, ScanOptions{Prefix: prefix}, fn)
+	return err
+}
+
+// MemoryDB is a sorted in-memory Sink and Source for tests and dry runs. Writes overwrite existing keys.
+type MemoryDB struct {
+	mu  sync.RWMutex
+	kvs []KeyValue
+}
+
+// NewMemoryDB creates an empty MemoryDB.
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{kvs: make([]KeyValue, 0)}
+}
+
+// search returns the index of key, or of the first key after it when it does not exist.
+func (m *MemoryDB) search(key []byte) int {
+	return sort.Search(len(m.kvs), func(i int) bool {
+		return bytes.Compare(m.kvs[i].Key, key) >= 0
+	})
+}
+
+// Set sets a copy of key and value.
+func (m *MemoryDB) Set(key, value []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(key, value)
+}
+
+func (m *MemoryDB) set(key, value []byte) {
+	kv := KeyValue{Key: append([]byte{}, key...), Value: append([]byte{}, value...)}
+	i := m.search(key)
+	if i < len(m.kvs) && bytes.Equal(m.kvs[i].Key, key) {
+		m.kvs[i] = kv
+		return
+	}
+	m.kvs = append(m.kvs, KeyValue{})
+	copy(m.kvs[i+1:], m.kvs[i:])
+	m.kvs[i] = kv
+}
+
+// Get returns the value of key. It returns badger.ErrKeyNotFound when the key does not exist.
+func (m *MemoryDB) Get(key []byte) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	i := m.search(key)
+	if i < len(m.kvs) && bytes.Equal(m.kvs[i].Key, key) {
+		return m.kvs[i].Value, nil
+	}
+	return nil, badger.ErrKeyNotFound
+}
+
+// Len returns the number of keys.
+func (m *MemoryDB) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.kvs)
+}
+
+// WriteBatch sets every key/value of kvs at once.
+func (m *MemoryDB) WriteBatch(kvs []KeyValue, done func(int32, error)) {
+	m.mu.Lock()
+	for _, kv := range kvs {
+		m.set(kv.Key, kv.Value)
+	}
+	m.mu.Unlock()
+	done(int32(len(kvs)), nil)
+}
+
+// Iterate calls fn for each key/value with prefix as of the start of the iteration.
+func (m *MemoryDB) Iterate(prefix []byte, fn func(*KeyValue) error) error {
+	m.mu.RLock()
+	kvs := m.kvs[m.search(prefix):]
+	end := sort.Search(len(kvs), func(i int) bool {
+		return !bytes.HasPrefix(kvs[i].Key, prefix)
+	})
+	snapshot := make([]KeyValue, end)
+	copy(snapshot, kvs)
+	m.mu.RUnlock()
+
+	for i := range snapshot {
+		kv := snapshot[i]
+		if err := fn(&kv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
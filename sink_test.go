@@ -0,0 +1,91 @@
+package badgerutils
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/dgraph-io/badger"
+	"github.com/stretchr/testify/require"
+)
+
+func iterateKeys(t *testing.T, source Source, prefix string) []string {
+	keys := make([]string, 0)
+	err := source.Iterate([]byte(prefix), func(kv *KeyValue) error {
+		keys = append(keys, string(kv.Key))
+		return nil
+	})
+	require.Nil(t, err)
+	return keys
+}
+
+func TestMemoryDB(t *testing.T) {
+	m := NewMemoryDB()
+	m.Set([]byte("b1"), []byte("1"))
+	m.Set([]byte("a1"), []byte("1"))
+	m.Set([]byte("b2"), []byte("2"))
+	m.Set([]byte("b1"), []byte("one"))
+	m.Set([]byte("c1"), []byte("1"))
+
+	require.Equal(t, 4, m.Len())
+	value, err := m.Get([]byte("b1"))
+	require.Nil(t, err)
+	require.Equal(t, "one", string(value))
+	_, err = m.Get([]byte("b3"))
+	require.Equal(t, badger.ErrKeyNotFound, err)
+
+	require.Equal(t, []string{"a1", "b1", "b2", "c1"}, iterateKeys(t, m, ""))
+	require.Equal(t, []string{"b1", "b2"}, iterateKeys(t, m, "b"))
+	require.Equal(t, []string{}, iterateKeys(t, m, "d"))
+}
+
+func TestWriteStreamTo(t *testing.T) {
+	m := NewMemoryDB()
+	reader := strings.NewReader("key2:value2\nkey1:value1\nkey3:value3")
+	result, err := WriteStreamTo(context.Background(), reader, m, 2, csvToKeyValue, nil)
+	require.Nil(t, err)
+	require.Equal(t, 3, result.Records)
+	require.Equal(t, 2, result.Batches)
+	require.Equal(t, []string{"key1", "key2", "key3"}, iterateKeys(t, m, "key"))
+}
+
+func TestBadgerSinkAndSource(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(path.Join(tmpDir, "db"))
+	require.Nil(t, err)
+	defer db.Close()
+
+	sink := &BadgerSink{DB: db, Conflict: ConflictSkip}
+	write := func(kvs []KeyValue) (int32, error) {
+		result := make(chan error, 1)
+		var written int32
+		sink.WriteBatch(kvs, func(n int32, err error) {
+			written = n
+			result <- err
+		})
+		// written is set before the result is sent, so it is read only once the result is received
+		err := <-result
+		return written, err
+	}
+
+	written, err := write([]KeyValue{{Key: []byte("b"), Value: []byte("1")}, {Key: []byte("a"), Value: []byte("1")}})
+	require.Nil(t, err)
+	require.Equal(t, int32(2), written)
+	written, err = write([]KeyValue{{Key: []byte("a"), Value: []byte("2")}, {Key: []byte("c"), Value: []byte("2")}})
+	require.Nil(t, err)
+	require.Equal(t, int32(1), written)
+
+	source := &BadgerSource{DB: db}
+	require.Equal(t, []string{"a", "b", "c"}, iterateKeys(t, source, ""))
+	value, err := Get(db, []byte("a"))
+	require.Nil(t, err)
+	require.Equal(t, "1", string(value))
+}
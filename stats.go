@@ -0,0 +1,238 @@
+package badgerutils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/bits"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/dgraph-io/badger"
+)
+
+// Histogram counts values in power of two buckets.
+type Histogram struct {
+	Count   int               `json:"count"`
+	Sum     int64             `json:"sum"`
+	Min     int64             `json:"min"`
+	Max     int64             `json:"max"`
+	Buckets []HistogramBucket `json:"buckets"`
+}
+
+// HistogramBucket counts the values greater than the previous bucket's UpperBound and at most UpperBound.
+type HistogramBucket struct {
+	UpperBound int64 `json:"upper_bound"`
+	Count      int   `json:"count"`
+}
+
+func (h *Histogram) observe(v int64) {
+	if h.Count == 0 || v < h.Min {
+		h.Min = v
+	}
+	if v > h.Max {
+		h.Max = v
+	}
+	h.Count++
+	h.Sum += v
+
+	bucket := 0
+	if v > 1 {
+		bucket = bits.Len64(uint64(v - 1))
+	}
+	for len(h.Buckets) <= bucket {
+		h.Buckets = append(h.Buckets, HistogramBucket{UpperBound: int64(1) << uint(len(h.Buckets))})
+	}
+	h.Buckets[bucket].Count++
+}
+
+// Mean returns the average of the observed values.
+func (h *Histogram) Mean() float64 {
+	if h.Count == 0 {
+		return 0
+	}
+	return float64(h.Sum) / float64(h.Count)
+}
+
+// PrefixStats is the cardinality and size of the keys sharing a prefix.
+type PrefixStats struct {
+	Prefix string `json:"prefix"`
+	Keys   int    `json:"keys"`
+	Bytes  int64  `json:"bytes"`
+}
+
+// DBStats is a report of the size and key space of a Badger.
+type DBStats struct {
+	LSMSize        int64         `json:"lsm_size"`
+	VlogSize       int64         `json:"vlog_size"`
+	TablesPerLevel []int         `json:"tables_per_level"`
+	Keys           int           `json:"keys"`
+	LiveKeys       int           `json:"live_keys"`
+	DeletedKeys    int           `json:"deleted_or_expired_keys"`
+	Versions       int           `json:"versions"`
+	VersionsPerKey Histogram     `json:"versions_per_key"`
+	KeySizes       Histogram     `json:"key_sizes"`
+	ValueSizes     Histogram     `json:"value_sizes"`
+	Prefixes       []PrefixStats `json:"prefixes"`
+}
+
+// StatsOptions defines how Stats groups keys into prefixes.
+type StatsOptions struct {
+	// PrefixDelimiter splits keys into segments. When empty, each byte is a segment.
+	PrefixDelimiter string
+	// PrefixDepth is the number of segments that make up a prefix. Prefixes are not reported when 0.
+	PrefixDepth int
+}
+
+func (opts StatsOptions) prefix(key []byte) []byte {
+	if opts.PrefixDelimiter == "" {
+		if len(key) < opts.PrefixDepth {
+			return key
+		}
+		return key[:opts.PrefixDepth]
+	}
+
+	delimiter := []byte(opts.PrefixDelimiter)
+	end := 0
+	for i := 0; i < opts.PrefixDepth; i++ {
+		next := bytes.Index(key[end:], delimiter)
+		if next < 0 {
+			return key
+		}
+		end += next + len(delimiter)
+	}
+	return key[:end]
+}
+
+// Stats scans every version of every key in the Badger at dir and reports its sizes and key space.
+// Value sizes are estimated from the LSM tree so values are not read from the value log. A missing dir is an error.
+func Stats(dir string, opts StatsOptions) (*DBStats, error) {
+	db, err := openExistingDB(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return collectStats(db, opts)
+}
+
+func collectStats(db *badger.DB, opts StatsOptions) (*DBStats, error) {
+	stats := &DBStats{}
+	stats.LSMSize, stats.VlogSize = db.Size()
+	for _, t := range db.Tables() {
+		for len(stats.TablesPerLevel) <= t.Level {
+			stats.TablesPerLevel = append(stats.TablesPerLevel, 0)
+		}
+		stats.TablesPerLevel[t.Level]++
+	}
+
+	prefixes := make(map[string]*PrefixStats)
+	err := db.View(func(txn *badger.Txn) error {
+		iteratorOpts := badger.DefaultIteratorOptions
+		iteratorOpts.AllVersions = true
+		iteratorOpts.PrefetchValues = false
+		it := txn.NewIterator(iteratorOpts)
+		defer it.Close()
+
+		var lastKey []byte
+		versions := 0
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			stats.Versions++
+			if lastKey != nil && bytes.Equal(item.Key(), lastKey) {
+				versions++
+				continue
+			}
+			if lastKey != nil {
+				stats.VersionsPerKey.observe(int64(versions))
+			}
+			lastKey = item.KeyCopy(lastKey[:0])
+			versions = 1
+			stats.Keys++
+
+			// The first version of a key is the newest and determines whether the key is live
+			if item.IsDeletedOrExpired() {
+				stats.DeletedKeys++
+				continue
+			}
+			stats.LiveKeys++
+
+			keySize := int64(len(item.Key()))
+			valueSize := item.EstimatedSize() - keySize
+			if valueSize < 0 {
+				valueSize = 0
+			}
+			stats.KeySizes.observe(keySize)
+			stats.ValueSizes.observe(valueSize)
+
+			if opts.PrefixDepth > 0 {
+				prefix := string(opts.prefix(item.Key()))
+				ps, ok := prefixes[prefix]
+				if !ok {
+					ps = &PrefixStats{Prefix: prefix}
+					prefixes[prefix] = ps
+				}
+				ps.Keys++
+				ps.Bytes += keySize + valueSize
+			}
+		}
+		if lastKey != nil {
+			stats.VersionsPerKey.observe(int64(versions))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Prefixes = make([]PrefixStats, 0, len(prefixes))
+	for _, ps := range prefixes {
+		stats.Prefixes = append(stats.Prefixes, *ps)
+	}
+	sort.Slice(stats.Prefixes, func(i, j int) bool {
+		return stats.Prefixes[i].Prefix < stats.Prefixes[j].Prefix
+	})
+	return stats, nil
+}
+
+// WriteText writes the report as human-readable tables.
+func (s *DBStats) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "LSM size\t%v\n", s.LSMSize)
+	fmt.Fprintf(tw, "Vlog size\t%v\n", s.VlogSize)
+	for level, tables := range s.TablesPerLevel {
+		fmt.Fprintf(tw, "Level %v tables\t%v\n", level, tables)
+	}
+	fmt.Fprintf(tw, "Keys\t%v\n", s.Keys)
+	fmt.Fprintf(tw, "Live keys\t%v\n", s.LiveKeys)
+	fmt.Fprintf(tw, "Deleted or expired keys\t%v\n", s.DeletedKeys)
+	fmt.Fprintf(tw, "Versions\t%v\n", s.Versions)
+	fmt.Fprintln(tw)
+
+	histograms := []struct {
+		name string
+		h    *Histogram
+	}{
+		{"Versions per key", &s.VersionsPerKey},
+		{"Key size", &s.KeySizes},
+		{"Value size", &s.ValueSizes},
+	}
+	for _, hist := range histograms {
+		fmt.Fprintf(tw, "%v\tcount=%v\tmin=%v\tmax=%v\tmean=%.1f\n",
+			hist.name, hist.h.Count, hist.h.Min, hist.h.Max, hist.h.Mean())
+		for _, b := range hist.h.Buckets {
+			if b.Count > 0 {
+				fmt.Fprintf(tw, "  <= %v\t%v\n", b.UpperBound, b.Count)
+			}
+		}
+	}
+
+	if len(s.Prefixes) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintf(tw, "Prefix\tKeys\tBytes\n")
+		for _, p := range s.Prefixes {
+			fmt.Fprintf(tw, "%q\t%v\t%v\n", p.Prefix, p.Keys, p.Bytes)
+		}
+	}
+	return tw.Flush()
+}
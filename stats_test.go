@@ -0,0 +1,68 @@
+package badgerutils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/dgraph-io/badger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsOptionsPrefix(t *testing.T) {
+	opts := StatsOptions{PrefixDelimiter: "/", PrefixDepth: 2}
+	require.Equal(t, []byte("a/b/"), opts.prefix([]byte("a/b/c")))
+	require.Equal(t, []byte("a/b"), opts.prefix([]byte("a/b")))
+
+	opts = StatsOptions{PrefixDepth: 3}
+	require.Equal(t, []byte("abc"), opts.prefix([]byte("abcdef")))
+	require.Equal(t, []byte("ab"), opts.prefix([]byte("ab")))
+}
+
+func TestStats(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := path.Join(tmpDir, "db")
+
+	err = WriteStream(strings.NewReader("spot/1:value1\nspot/2:value2\nbuoy/1:value3"), dbPath, 2, csvToKeyValue)
+	require.Nil(t, err)
+	err = WriteStream(strings.NewReader("spot/1:value1b"), dbPath, 2, csvToKeyValue)
+	require.Nil(t, err)
+
+	db, err := openDB(dbPath)
+	require.Nil(t, err)
+	err = db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte("buoy/1"))
+	})
+	require.Nil(t, err)
+	require.Nil(t, db.Close())
+
+	stats, err := Stats(dbPath, StatsOptions{PrefixDelimiter: "/", PrefixDepth: 1})
+	require.Nil(t, err)
+	require.Equal(t, 3, stats.Keys)
+	require.Equal(t, 2, stats.LiveKeys)
+	require.Equal(t, 1, stats.DeletedKeys)
+	require.Equal(t, 5, stats.Versions)
+	require.Equal(t, 3, stats.VersionsPerKey.Count)
+	require.Equal(t, int64(2), stats.VersionsPerKey.Max)
+	require.Equal(t, 2, stats.KeySizes.Count)
+	require.Equal(t, int64(6), stats.KeySizes.Min)
+	require.Equal(t, []PrefixStats{{Prefix: "spot/", Keys: 2, Bytes: stats.KeySizes.Sum + stats.ValueSizes.Sum}}, stats.Prefixes)
+
+	var buf bytes.Buffer
+	require.Nil(t, stats.WriteText(&buf))
+	require.Contains(t, buf.String(), `"spot/"`)
+
+	missing := path.Join(tmpDir, "missing")
+	_, err = Stats(missing, StatsOptions{})
+	require.EqualError(t, err, "no DB directory at "+missing)
+	_, err = os.Stat(missing)
+	require.True(t, os.IsNotExist(err))
+}
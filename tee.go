@@ -0,0 +1,134 @@
+package badgerutils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Destination is one of the targets written by WriteStreamMulti and Pipeline.ToDestinations.
+type Destination struct {
+	// Dir is the directory of the Badger written by WriteStreamMulti when Sink is nil.
+	Dir string
+	// Sink receives the batches of the destination.
+	Sink Sink
+	// Filter drops the key/values for which it returns false. Every key/value is written when nil.
+	Filter func(*KeyValue) bool
+	// Transform rewrites each key/value, or drops it when it returns nil. A Transform error stops writing to the
+	// destination only. Each destination is passed its own copy of the key/value, so Transform may modify it.
+	Transform func(*KeyValue) (*KeyValue, error)
+	// Hooks is called with the batches and the result of the destination. It may be nil.
+	Hooks WriteHooks
+}
+
+// name identifies the destination in errors and logs.
+func (d *Destination) name(i int) string {
+	if d.Dir != "" {
+		return d.Dir
+	}
+	return fmt.Sprintf("destination %v", i)
+}
+
+// WriteStreamMulti reads and parses the lines of reader once and writes the key/values into every destination.
+// Each destination is batched and committed independently, so a destination that fails does not stop the others.
+// It returns the result of each destination, and an error when reading failed or any destination failed.
+func WriteStreamMulti(ctx context.Context, reader io.Reader, destinations []Destination, batchSize int,
+	lineToKeyValue func(string) (*KeyValue, error)) ([]WriteResult, error) {
+	dests := make([]Destination, len(destinations))
+	copy(dests, destinations)
+	for i := range dests {
+		if dests[i].Sink != nil {
+			continue
+		}
+		db, err := Open(dests[i].Dir)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", dests[i].name(i), err)
+		}
+		defer db.Close()
+		dests[i].Sink = NewBadgerSink(db)
+	}
+
+	return From(reader, lineToKeyValue).BatchSize(batchSize).ToDestinations(ctx, dests)
+}
+
+// ToDestinations runs the pipeline and writes its key/values to every destination, which must each have a Sink.
+// The pipeline's hooks are called as records are parsed or rejected, and each destination's hooks with its batches
+// and result.
+func (p *Pipeline) ToDestinations(ctx context.Context, destinations []Destination) ([]WriteResult, error) {
+	start := time.Now()
+	results := make([]WriteResult, len(destinations))
+	writers := make([]*batchWriter, len(destinations))
+	failed := make([]error, len(destinations))
+	for i, dest := range destinations {
+		writers[i] = newBatchWriter(dest.Sink, p.batchSize)
+		writers[i].ctx = ctx
+		if dest.Hooks != nil {
+			writers[i].hooks = dest.Hooks
+		}
+	}
+
+	var shared WriteResult
+	emit, reject := p.chain(ctx, &shared, func(kv *KeyValue) error {
+		for i, dest := range destinations {
+			if failed[i] != nil {
+				continue
+			}
+			// Destinations must not see each other's changes to the key/value
+			c := &KeyValue{Key: append([]byte{}, kv.Key...), Value: append([]byte{}, kv.Value...)}
+			if dest.Filter != nil && !dest.Filter(c) {
+				continue
+			}
+			out := c
+			if dest.Transform != nil {
+				var err error
+				if out, err = dest.Transform(c); err != nil {
+					failed[i] = err
+					continue
+				}
+				if out == nil {
+					continue
+				}
+			}
+			writers[i].add(*out)
+		}
+		return nil
+	})
+
+	// Write remaining key/values of every destination even if reading failed so no batches are left running
+	readErr := p.read(emit, reject)
+	errs := make([]string, 0)
+	for i, w := range writers {
+		err := failed[i]
+		if flushErr := w.flush(); flushErr != nil && err == nil {
+			err = flushErr
+		}
+		if err == nil {
+			err = readErr
+		}
+		if err != nil && err != readErr {
+			errs = append(errs, fmt.Sprintf("%v: %v", destinations[i].name(i), err))
+		}
+
+		results[i] = WriteResult{
+			Records:  int(w.kvCount.get()),
+			Batches:  w.batches,
+			Rejected: shared.Rejected,
+			Elapsed:  time.Since(start),
+			Err:      err,
+		}
+		w.hooks.OnComplete(ctx, results[i])
+		DefaultLogger.Log(LevelInfo, "Inserted records", "destination", destinations[i].name(i),
+			"records", results[i].Records, "elapsed", results[i].Elapsed)
+	}
+
+	if readErr != nil {
+		return results, readErr
+	}
+	if len(errs) > 0 {
+		return results, fmt.Errorf("%v of %v destinations failed:\n%v", len(errs), len(destinations),
+			strings.Join(errs, "\n"))
+	}
+	return results, nil
+}
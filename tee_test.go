@@ -0,0 +1,111 @@
+package badgerutils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteStreamMulti(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	all := path.Join(tmpDir, "all")
+	even := path.Join(tmpDir, "even")
+	transformed := NewMemoryDB()
+	failing := NewMemoryDB()
+
+	reader := strings.NewReader("key1:value1\nkey2:value2\nkey3:value3\nkey4:value4")
+	results, err := WriteStreamMulti(context.Background(), reader, []Destination{
+		{Dir: all},
+		{Dir: even, Filter: func(kv *KeyValue) bool {
+			return (kv.Key[len(kv.Key)-1]-'0')%2 == 0
+		}},
+		{Sink: transformed, Transform: func(kv *KeyValue) (*KeyValue, error) {
+			if string(kv.Key) == "key1" {
+				return nil, nil
+			}
+			return &KeyValue{Key: kv.Key, Value: bytes.ToUpper(kv.Value)}, nil
+		}},
+		{Sink: failing, Transform: func(kv *KeyValue) (*KeyValue, error) {
+			if string(kv.Key) == "key3" {
+				return nil, fmt.Errorf("cannot transform %s", kv.Key)
+			}
+			return kv, nil
+		}},
+	}, 2, csvToKeyValue)
+	require.NotNil(t, err)
+	require.Equal(t, "1 of 4 destinations failed:\ndestination 3: cannot transform key3", err.Error())
+
+	require.Equal(t, 4, len(results))
+	require.Equal(t, 4, results[0].Records)
+	require.Nil(t, results[0].Err)
+	require.Equal(t, 2, results[1].Records)
+	require.Equal(t, 3, results[2].Records)
+	require.Equal(t, 2, results[3].Records)
+	require.NotNil(t, results[3].Err)
+
+	records, err := readDB(all)
+	require.Nil(t, err)
+	require.Equal(t, 4, len(records))
+	records, err = readDB(even)
+	require.Nil(t, err)
+	require.Equal(t, []sampleRecord{{Key: "key2", Value: "value2"}, {Key: "key4", Value: "value4"}}, records)
+
+	value, err := transformed.Get([]byte("key2"))
+	require.Nil(t, err)
+	require.Equal(t, "VALUE2", string(value))
+	require.Equal(t, 3, transformed.Len())
+	require.Equal(t, 2, failing.Len())
+
+	// A parse error fails every destination
+	results, err = WriteStreamMulti(context.Background(), strings.NewReader("key5:value5\ninvalid"),
+		[]Destination{{Sink: NewMemoryDB()}, {Sink: NewMemoryDB()}}, 2, csvToKeyValue)
+	require.NotNil(t, err)
+	for _, result := range results {
+		require.Equal(t, 1, result.Records)
+		require.Equal(t, 1, result.Rejected)
+		require.Equal(t, err, result.Err)
+	}
+}
+
+func TestToDestinationsCopies(t *testing.T) {
+	// Each transform modifies its key/value in place
+	prefixer := func(prefix string) func(*KeyValue) (*KeyValue, error) {
+		return func(kv *KeyValue) (*KeyValue, error) {
+			kv.Key = append([]byte(prefix), kv.Key...)
+			kv.Value[0] = prefix[0]
+			return kv, nil
+		}
+	}
+	a, b, plain := NewMemoryDB(), NewMemoryDB(), NewMemoryDB()
+	_, err := From(strings.NewReader("key1:value1\nkey2:value2"), csvToKeyValue).ToDestinations(context.Background(),
+		[]Destination{
+			{Sink: a, Transform: prefixer("a/")},
+			{Sink: b, Transform: prefixer("b/"), Filter: func(kv *KeyValue) bool { return kv.Value[0] == 'v' }},
+			{Sink: plain},
+		})
+	require.Nil(t, err)
+
+	contents := func(m *MemoryDB) []string {
+		kvs := make([]string, 0)
+		require.Nil(t, m.Iterate(nil, func(kv *KeyValue) error {
+			kvs = append(kvs, fmt.Sprintf("%s=%s", kv.Key, kv.Value))
+			return nil
+		}))
+		return kvs
+	}
+	require.Equal(t, []string{"a/key1=aalue1", "a/key2=aalue2"}, contents(a))
+	require.Equal(t, []string{"b/key1=balue1", "b/key2=balue2"}, contents(b))
+	require.Equal(t, []string{"key1=value1", "key2=value2"}, contents(plain))
+}
@@ -0,0 +1,220 @@
+// Package timeseries stores series of timestamped values in Badger under keys made of the series ID and the
+// big-endian timestamp, so that the points of a series are contiguous and in time order.
+package timeseries
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Surfline/badgerutils"
+	"github.com/Surfline/badgerutils/keys"
+	"github.com/dgraph-io/badger"
+)
+
+// Point is a value of a series at a time.
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// Sample is a point of the series ID.
+type Sample struct {
+	Series string `json:"series"`
+	Point
+}
+
+// Bucket is the aggregate of the points of a series in [Start, Start+width).
+type Bucket struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Sum   float64   `json:"sum"`
+}
+
+// Mean returns the mean of the points of the bucket.
+func (b Bucket) Mean() float64 {
+	return b.Sum / float64(b.Count)
+}
+
+// Store reads and writes the series of a Badger under a key prefix. Keys are the prefix, the series ID encoded with
+// keys.AppendString and the timestamp encoded with keys.AppendTime, and values are the 8 big-endian bytes of the
+// IEEE 754 value. A point overwrites another point of the same series at the same time.
+type Store struct {
+	db     *badger.DB
+	prefix []byte
+}
+
+// NewStore creates a Store for the series of db under prefix.
+func NewStore(db *badger.DB, prefix []byte) *Store {
+	return &Store{db: db, prefix: append([]byte{}, prefix...)}
+}
+
+// seriesPrefix returns the prefix of the keys of the series id.
+func (s *Store) seriesPrefix(id string) []byte {
+	return keys.AppendString(append([]byte{}, s.prefix...), id)
+}
+
+// Key returns the key of the point of the series id at t.
+func (s *Store) Key(id string, t time.Time) []byte {
+	return keys.AppendTime(s.seriesPrefix(id), t)
+}
+
+// ParseKey returns the series ID and the time of a key built by Key.
+func (s *Store) ParseKey(key []byte) (string, time.Time, error) {
+	if !bytes.HasPrefix(key, s.prefix) {
+		return "", time.Time{}, fmt.Errorf("key %q does not have prefix %q", key, s.prefix)
+	}
+	id, rest, err := keys.DecodeString(key[len(s.prefix):])
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	t, _, err := keys.DecodeTime(rest)
+	return id, t, err
+}
+
+// KeyValue returns the key/value of sample. Keys hold times from keys.MinTime to keys.MaxTime, so samples outside
+// them, including samples with the zero time.Time, are rejected.
+func (s *Store) KeyValue(sample Sample) (*badgerutils.KeyValue, error) {
+	if !keys.ValidTime(sample.Time) {
+		return nil, fmt.Errorf("time %v of series %q is outside %v to %v", sample.Time, sample.Series,
+			keys.MinTime, keys.MaxTime)
+	}
+	return &badgerutils.KeyValue{
+		Key:   s.Key(sample.Series, sample.Time),
+		Value: keys.AppendUint64(nil, math.Float64bits(sample.Value)),
+	}, nil
+}
+
+// point decodes the point of a key/value of the series whose keys start with seriesPrefix.
+func point(seriesPrefix []byte, kv *badgerutils.KeyValue) (Point, error) {
+	t, _, err := keys.DecodeTime(kv.Key[len(seriesPrefix):])
+	if err != nil {
+		return Point{}, fmt.Errorf("key %q: %v", kv.Key, err)
+	}
+	bits, _, err := keys.DecodeUint64(kv.Value)
+	if err != nil {
+		return Point{}, fmt.Errorf("value of key %q: %v", kv.Key, err)
+	}
+	return Point{Time: t, Value: math.Float64frombits(bits)}, nil
+}
+
+// Parser adapts fn, which reads one sample from a line of input, to the line parsers of badgerutils.WriteStream.
+func (s *Store) Parser(fn func(line string) (Sample, error)) func(string) (*badgerutils.KeyValue, error) {
+	return func(line string) (*badgerutils.KeyValue, error) {
+		sample, err := fn(line)
+		if err != nil {
+			return nil, err
+		}
+		return s.KeyValue(sample)
+	}
+}
+
+// Append writes samples in batches of batchSize through the badgerutils writer, committing batches concurrently.
+// It stops at the first sample that KeyValue rejects, which is counted as a rejected record.
+func (s *Store) Append(ctx context.Context, samples []Sample, batchSize int) (badgerutils.WriteResult, error) {
+	return badgerutils.NewPipeline(func(emit func(*badgerutils.KeyValue) error, reject func(string, error)) error {
+		for _, sample := range samples {
+			kv, err := s.KeyValue(sample)
+			if err != nil {
+				reject(sample.Series, err)
+				return err
+			}
+			if err := emit(kv); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).BatchSize(batchSize).ToContext(ctx, badgerutils.NewBadgerSink(s.db))
+}
+
+// Window calls fn for each point of the series id in [from, to) in time order.
+func (s *Store) Window(id string, from, to time.Time, fn func(Point) error) error {
+	seriesPrefix := s.seriesPrefix(id)
+	r := keys.Range{Start: keys.AppendTime(s.seriesPrefix(id), from), End: keys.AppendTime(s.seriesPrefix(id), to)}
+	return keys.ScanRange(s.db, r, false, func(kv *badgerutils.KeyValue) error {
+		p, err := point(seriesPrefix, kv)
+		if err != nil {
+			return err
+		}
+		return fn(p)
+	})
+}
+
+// Latest returns the last n points of the series id in time order, reading the series backwards from its end. It
+// returns no points when n is not positive.
+func (s *Store) Latest(id string, n int) ([]Point, error) {
+	if n <= 0 {
+		return []Point{}, nil
+	}
+	seriesPrefix := s.seriesPrefix(id)
+	points := make([]Point, 0, n)
+	err := s.db.View(func(txn *badger.Txn) error {
+		iteratorOpts := badger.DefaultIteratorOptions
+		iteratorOpts.Reverse = true
+		it := txn.NewIterator(iteratorOpts)
+		defer it.Close()
+
+		// Timestamps are 8 bytes, so the 9 bytes of 0xFF sort after every key of the series
+		seek := append(append([]byte{}, seriesPrefix...), bytes.Repeat([]byte{0xFF}, 9)...)
+		for it.Seek(seek); it.ValidForPrefix(seriesPrefix) && len(points) < n; it.Next() {
+			item := it.Item()
+			value, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			p, err := point(seriesPrefix, &badgerutils.KeyValue{Key: item.KeyCopy(nil), Value: value})
+			if err != nil {
+				return err
+			}
+			points = append(points, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+	return points, nil
+}
+
+// Aggregate calls fn with the aggregate of each bucket of width of the points of the series id in [from, to), in
+// time order. Buckets start at multiples of width since the zero time, so daily buckets start at midnight UTC, and
+// buckets without points are skipped. Points are aggregated while scanning, so only one bucket is held in memory.
+func (s *Store) Aggregate(id string, from, to time.Time, width time.Duration, fn func(Bucket) error) error {
+	if width <= 0 {
+		return fmt.Errorf("bucket width must be positive, got %v", width)
+	}
+
+	var bucket *Bucket
+	err := s.Window(id, from, to, func(p Point) error {
+		start := p.Time.Truncate(width)
+		if bucket != nil && !bucket.Start.Equal(start) {
+			if err := fn(*bucket); err != nil {
+				return err
+			}
+			bucket = nil
+		}
+		if bucket == nil {
+			bucket = &Bucket{Start: start, Min: p.Value, Max: p.Value}
+		}
+		bucket.Count++
+		bucket.Min = math.Min(bucket.Min, p.Value)
+		bucket.Max = math.Max(bucket.Max, p.Value)
+		bucket.Sum += p.Value
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if bucket != nil {
+		return fn(*bucket)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package timeseries
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Surfline/badgerutils"
+	"github.com/Surfline/badgerutils/badgerutilstest"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+// seedStore appends hourly points for 10 days to buoy-1 and buoy-10, whose ID is an extension of the first.
+func seedStore(t *testing.T) (*Store, []Sample) {
+	store := NewStore(badgerutilstest.TempDB(t), []byte("ts/"))
+	samples := make([]Sample, 0)
+	for i := 0; i < 240; i++ {
+		at := start.Add(time.Duration(i) * time.Hour)
+		samples = append(samples,
+			Sample{Series: "buoy-1", Point: Point{Time: at, Value: math.Sin(float64(i)) * 10}},
+			Sample{Series: "buoy-10", Point: Point{Time: at, Value: float64(-i)}})
+	}
+	result, err := store.Append(context.Background(), samples, 50)
+	require.Nil(t, err)
+	require.Equal(t, len(samples), result.Records)
+	require.Equal(t, 10, result.Batches)
+	return store, samples
+}
+
+func TestKeys(t *testing.T) {
+	store := NewStore(nil, []byte("ts/"))
+	at := time.Date(2026, 10, 18, 6, 0, 0, 0, time.UTC)
+	id, parsed, err := store.ParseKey(store.Key("buoy\x001", at))
+	require.Nil(t, err)
+	require.Equal(t, "buoy\x001", id)
+	require.Equal(t, at, parsed)
+
+	_, _, err = store.ParseKey([]byte("other/key"))
+	require.NotNil(t, err)
+
+	// Times whose nanoseconds do not fit the key are rejected instead of wrapping around
+	for _, at := range []time.Time{{}, time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC)} {
+		_, err = store.KeyValue(Sample{Series: "buoy-1", Point: Point{Time: at}})
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "is outside")
+	}
+	kv, err := store.KeyValue(Sample{Series: "buoy-1", Point: Point{Time: at, Value: 1.5}})
+	require.Nil(t, err)
+	require.Equal(t, store.Key("buoy-1", at), kv.Key)
+}
+
+func TestWindow(t *testing.T) {
+	store, samples := seedStore(t)
+	from, to := start.Add(24*time.Hour), start.Add(30*time.Hour)
+
+	points := make([]Point, 0)
+	require.Nil(t, store.Window("buoy-1", from, to, func(p Point) error {
+		points = append(points, p)
+		return nil
+	}))
+	expected := make([]Point, 0)
+	for _, sample := range samples {
+		if sample.Series == "buoy-1" && !sample.Time.Before(from) && sample.Time.Before(to) {
+			expected = append(expected, sample.Point)
+		}
+	}
+	require.Len(t, expected, 6)
+	require.Equal(t, expected, points)
+
+	// Bounds outside the times keys can hold are clamped, so the zero time reads from the start of the series
+	count := 0
+	require.Nil(t, store.Window("buoy-1", time.Time{}, time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC), func(Point) error {
+		count++
+		return nil
+	}))
+	require.Equal(t, 240, count)
+
+	err := store.Window("buoy-1", from, to, func(p Point) error { return fmt.Errorf("stop") })
+	require.EqualError(t, err, "stop")
+}
+
+func TestLatest(t *testing.T) {
+	store, _ := seedStore(t)
+
+	points, err := store.Latest("buoy-1", 3)
+	require.Nil(t, err)
+	require.Len(t, points, 3)
+	for i, p := range points {
+		require.Equal(t, start.Add(time.Duration(237+i)*time.Hour), p.Time)
+		require.Equal(t, math.Sin(float64(237+i))*10, p.Value)
+	}
+
+	points, err = store.Latest("buoy-10", 1000)
+	require.Nil(t, err)
+	require.Len(t, points, 240)
+	require.Equal(t, start, points[0].Time)
+
+	for _, n := range []int{0, -1} {
+		points, err = store.Latest("buoy-1", n)
+		require.Nil(t, err)
+		require.Empty(t, points)
+	}
+
+	points, err = store.Latest("buoy-2", 5)
+	require.Nil(t, err)
+	require.Empty(t, points)
+
+	// Samples written through the parser are read back as the latest points of their series
+	parse := store.Parser(func(line string) (Sample, error) {
+		fields := strings.Split(line, ",")
+		at, err := time.Parse(time.RFC3339, fields[1])
+		if err != nil {
+			return Sample{}, err
+		}
+		value, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return Sample{}, err
+		}
+		return Sample{Series: fields[0], Point: Point{Time: at, Value: value}}, nil
+	})
+	input := "buoy-2,2026-10-18T06:00:00Z,1.5\nbuoy-2,2026-10-18T07:00:00Z,2.5\n"
+	_, err = badgerutils.WriteStreamTo(context.Background(), strings.NewReader(input), badgerutils.NewBadgerSink(store.db),
+		10, parse, nil)
+	require.Nil(t, err)
+	points, err = store.Latest("buoy-2", 1)
+	require.Nil(t, err)
+	require.Equal(t, []Point{{Time: time.Date(2026, 10, 18, 7, 0, 0, 0, time.UTC), Value: 2.5}}, points)
+
+	result, err := store.Append(context.Background(), []Sample{{Series: "buoy-2"}}, 10)
+	require.NotNil(t, err)
+	require.Equal(t, 1, result.Rejected)
+}
+
+func TestAggregate(t *testing.T) {
+	store, samples := seedStore(t)
+
+	buckets := make([]Bucket, 0)
+	require.Nil(t, store.Aggregate("buoy-1", start.Add(12*time.Hour), start.Add(72*time.Hour), 24*time.Hour,
+		func(b Bucket) error {
+			buckets = append(buckets, b)
+			return nil
+		}))
+	require.Len(t, buckets, 3)
+
+	expected := make(map[time.Time]*Bucket)
+	for _, sample := range samples {
+		if sample.Series != "buoy-1" || sample.Time.Before(start.Add(12*time.Hour)) || !sample.Time.Before(start.Add(72*time.Hour)) {
+			continue
+		}
+		day := sample.Time.Truncate(24 * time.Hour)
+		b, ok := expected[day]
+		if !ok {
+			b = &Bucket{Start: day, Min: math.Inf(1), Max: math.Inf(-1)}
+			expected[day] = b
+		}
+		b.Count++
+		b.Min = math.Min(b.Min, sample.Value)
+		b.Max = math.Max(b.Max, sample.Value)
+		b.Sum += sample.Value
+	}
+	for i, b := range buckets {
+		require.Equal(t, start.Add(time.Duration(i)*24*time.Hour), b.Start)
+		require.Equal(t, *expected[b.Start], b)
+	}
+	require.Equal(t, 12, buckets[0].Count)
+	require.InDelta(t, buckets[1].Sum/24, buckets[1].Mean(), 1e-9)
+
+	err := store.Aggregate("buoy-1", start, start.Add(time.Hour), 0, func(Bucket) error { return nil })
+	require.NotNil(t, err)
+}
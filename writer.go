@@ -1,11 +1,9 @@
 package badgerutils
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"io"
-	"log"
-	"os"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -16,8 +14,8 @@ import (
 
 // KeyValue struct defines a Key and a Value empty interface to be translated into a record.
 type KeyValue struct {
-	Key   []byte
-	Value []byte
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
 }
 
 type count32 int32
@@ -30,93 +28,194 @@ func (c *count32) get() int32 {
 	return atomic.LoadInt32((*int32)(c))
 }
 
-func writeBatch(kvs []KeyValue, db *badger.DB, cherr chan error, done func(int32)) {
-	txn := db.NewTransaction(true)
-	defer txn.Discard()
+// ConflictPolicy defines how a write handles a key that already exists in the Badger.
+type ConflictPolicy int
 
-	for _, kv := range kvs {
-		if err := txn.Set(kv.Key, kv.Value); err != nil {
-			cherr <- err
-		}
+const (
+	// ConflictOverwrite replaces the existing value.
+	ConflictOverwrite ConflictPolicy = iota
+	// ConflictSkip keeps the existing value and drops the new one.
+	ConflictSkip
+	// ConflictError fails the batch containing the key.
+	ConflictError
+)
+
+// batchWriter groups key/values into batches that are written concurrently to a Sink.
+type batchWriter struct {
+	sink      Sink
+	batchSize int
+	ctx       context.Context
+	hooks     WriteHooks
+	batches   int
+
+	// Wait group ensures all transactions are committed before reading errors
+	wg      sync.WaitGroup
+	kvCount count32
+	kvBatch []KeyValue
+
+	mu   sync.Mutex
+	errs []string
+}
+
+func newBatchWriter(sink Sink, batchSize int) *batchWriter {
+	return &batchWriter{
+		sink:      sink,
+		batchSize: batchSize,
+		ctx:       context.Background(),
+		hooks:     NopHooks{},
+		kvBatch:   make([]KeyValue, 0),
 	}
+}
 
-	txn.Commit(func(err error) {
-		if err != nil {
-			cherr <- err
-		}
-		done(int32(len(kvs)))
-	})
+func (w *batchWriter) addError(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.errs = append(w.errs, fmt.Sprintf("%v", err))
 }
 
-// WriteStream translates io.Reader stream into key/value pairs that are written into the Badger.
-// lineToKeyValue function parameter defines how stdin is translated to a value and how to define a key
-// from that value.
-func WriteStream(reader io.Reader, dir string, batchSize int, lineToKeyValue func(string) (*KeyValue, error)) error {
-	if mkdirErr := os.MkdirAll(dir, os.ModePerm); mkdirErr != nil {
-		return mkdirErr
+func (w *batchWriter) done(processedCount int32) {
+	w.kvCount.increment(processedCount)
+	DefaultLogger.Log(LevelDebug, "Committed batch", "records", w.kvCount.get())
+	w.wg.Done()
+}
+
+// add appends a key/value to the current batch and commits the batch in the background once it is full.
+func (w *batchWriter) add(kv KeyValue) {
+	w.kvBatch = append(w.kvBatch, kv)
+	if len(w.kvBatch) >= w.batchSize {
+		w.batches++
+		w.wg.Add(1)
+		go w.writeBatch(w.batches, w.kvBatch)
+		w.kvBatch = make([]KeyValue, 0)
 	}
+}
 
-	db, dbErr := openDB(dir)
-	if dbErr != nil {
-		return dbErr
+// flush writes remaining key/values and waits for all transactions to be committed.
+func (w *batchWriter) flush() error {
+	if len(w.kvBatch) > 0 {
+		w.batches++
+		w.wg.Add(1)
+		w.writeBatch(w.batches, w.kvBatch)
+		w.kvBatch = make([]KeyValue, 0)
 	}
-	defer db.Close()
 
-	start := time.Now()
+	w.wg.Wait()
 
-	// Wait group ensures all transactions are committed before reading errors from channel
-	var wg sync.WaitGroup
-	var kvCount count32
-	done := func(processedCount int32) {
-		kvCount.increment(processedCount)
-		log.Printf("Records: %v\n", int32(kvCount))
-		wg.Done()
+	if len(w.errs) > 0 {
+		return fmt.Errorf("Errors inserting records:\n%v", strings.Join(w.errs, "\n"))
 	}
+	return nil
+}
 
-	kvBatch := make([]KeyValue, 0)
-	cherr := make(chan error)
+func (w *batchWriter) writeBatch(batch int, kvs []KeyValue) {
+	ctx := w.hooks.OnBatchStart(w.ctx, batch, len(kvs))
+	start := time.Now()
+	w.sink.WriteBatch(kvs, func(written int32, err error) {
+		w.hooks.OnBatchCommitted(ctx, batch, int(written), time.Since(start), err)
+		if err != nil {
+			w.addError(err)
+		}
+		w.done(written)
+	})
+}
 
-	// Read from stream and write key/values in batches
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
-		kv, err := lineToKeyValue(scanner.Text())
+// commitBatch writes kvs in a single transaction and calls done with the number of key/values written once the
+// transaction is committed or has failed. A batch is committed whole or not at all: a key that cannot be set, an
+// existing key under ConflictError or a failed commit fails the batch with 0 written. done is also called when
+// every key is skipped, as Badger does not call back for transactions without writes.
+func commitBatch(db *badger.DB, kvs []KeyValue, conflict ConflictPolicy, done func(int32, error)) {
+	start := time.Now()
+	bytesWritten := 0
+	DefaultMetrics.AddGauge(MetricTxnsInFlight, 1)
+	DefaultMetrics.Observe(MetricBatchSize, float64(len(kvs)))
+	callerDone := done
+	done = func(written int32, err error) {
+		DefaultMetrics.AddGauge(MetricTxnsInFlight, -1)
+		DefaultMetrics.Observe(MetricBatchCommitTime, time.Since(start).Seconds())
 		if err != nil {
-			return err
+			DefaultMetrics.IncCounter(MetricBatchCommitErrors, 1)
+		} else {
+			DefaultMetrics.IncCounter(MetricBatchCommits, 1)
+			DefaultMetrics.IncCounter(MetricRecordsWritten, float64(written))
+			DefaultMetrics.IncCounter(MetricBytesWritten, float64(bytesWritten))
 		}
-		kvBatch = append(kvBatch, *kv)
-		if len(kvBatch) == batchSize {
-			wg.Add(1)
-			go writeBatch(kvBatch, db, cherr, done)
-			kvBatch = make([]KeyValue, 0)
+		recordSize(db)
+		callerDone(written, err)
+	}
+
+	txn := db.NewTransaction(true)
+	defer txn.Discard()
+
+	written := int32(0)
+	for _, kv := range kvs {
+		if conflict != ConflictOverwrite {
+			_, err := txn.Get(kv.Key)
+			if err == nil && conflict == ConflictSkip {
+				continue
+			}
+			if err == nil {
+				err = fmt.Errorf("key %q already exists", kv.Key)
+			}
+			if err != badger.ErrKeyNotFound {
+				done(0, err)
+				return
+			}
 		}
+		if err := txn.Set(kv.Key, kv.Value); err != nil {
+			done(0, err)
+			return
+		}
+		written++
+		bytesWritten += len(kv.Key) + len(kv.Value)
 	}
 
-	// Write remaining key/values
-	if len(kvBatch) > 0 {
-		wg.Add(1)
-		writeBatch(kvBatch, db, cherr, done)
+	// Badger does not call back for transactions without writes
+	if written == 0 {
+		done(0, nil)
+		return
 	}
 
-	// Read and handle errors from stream
-	if streamErr := scanner.Err(); streamErr != nil {
-		return streamErr
+	err := txn.Commit(func(err error) {
+		if err != nil {
+			done(0, err)
+			return
+		}
+		done(written, nil)
+	})
+	if err != nil {
+		done(0, err)
 	}
+}
 
-	wg.Wait()
-	close(cherr)
+// WriteStream translates io.Reader stream into key/value pairs that are written into the Badger.
+// lineToKeyValue function parameter defines how stdin is translated to a value and how to define a key
+// from that value. The stream stops at the first line lineToKeyValue fails on and its error is returned, but the
+// records read before that line, including the last partial batch, are written before WriteStream returns.
+func WriteStream(reader io.Reader, dir string, batchSize int, lineToKeyValue func(string) (*KeyValue, error)) error {
+	_, err := WriteStreamContext(context.Background(), reader, dir, batchSize, lineToKeyValue, nil)
+	return err
+}
 
-	// Read and handle transaction errors
-	errs := make([]string, 0)
-	for err := range cherr {
-		errs = append(errs, fmt.Sprintf("%v", err))
+// WriteStreamContext is WriteStream with hooks that are called at each stage of the write. ctx is passed to
+// every hook, and reading stops with ctx.Err() once ctx is done. hooks may be nil.
+func WriteStreamContext(ctx context.Context, reader io.Reader, dir string, batchSize int,
+	lineToKeyValue func(string) (*KeyValue, error), hooks WriteHooks) (WriteResult, error) {
+	db, err := Open(dir)
+	if err != nil {
+		result := WriteResult{Err: err}
+		if hooks != nil {
+			hooks.OnComplete(ctx, result)
+		}
+		return result, err
 	}
+	defer db.Close()
 
-	if len(errs) > 0 {
-		return fmt.Errorf("Errors inserting records:\n%v", strings.Join(errs, "\n"))
-	}
+	return WriteStreamTo(ctx, reader, NewBadgerSink(db), batchSize, lineToKeyValue, hooks)
+}
 
-	end := time.Now()
-	elapsed := end.Sub(start)
-	log.Printf("Inserted %v records in %v", kvCount.get(), elapsed)
-	return nil
+// WriteStreamTo is WriteStreamContext writing into sink instead of a Badger directory, e.g. a MemoryDB for tests
+// and dry runs.
+func WriteStreamTo(ctx context.Context, reader io.Reader, sink Sink, batchSize int,
+	lineToKeyValue func(string) (*KeyValue, error), hooks WriteHooks) (WriteResult, error) {
+	return From(reader, lineToKeyValue).BatchSize(batchSize).Hooks(hooks).ToContext(ctx, sink)
 }
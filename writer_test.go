@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/dgraph-io/badger"
 	"github.com/stretchr/testify/require"
 )
 
@@ -41,3 +42,79 @@ key3:value3`)
 		Value: "value3",
 	})
 }
+
+func TestWriteStreamParseError(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := path.Join(tmpDir, "db")
+
+	// The records before the invalid line are written, including the partial batch holding key3
+	reader := strings.NewReader("key1:value1\nkey2:value2\nkey3:value3\ninvalid\nkey4:value4")
+	err = WriteStream(reader, dbPath, 2, csvToKeyValue)
+	require.EqualError(t, err, "invalid has less than 2 kv")
+
+	records, err := readDB(dbPath)
+	require.Nil(t, err)
+	require.Equal(t, []sampleRecord{
+		{Key: "key1", Value: "value1"},
+		{Key: "key2", Value: "value2"},
+		{Key: "key3", Value: "value3"},
+	}, records)
+}
+
+func TestCommitBatch(t *testing.T) {
+	dir, err := os.Getwd()
+	require.Nil(t, err)
+	tmpDir, err := ioutil.TempDir(dir, "temp")
+	require.Nil(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(path.Join(tmpDir, "db"))
+	require.Nil(t, err)
+	defer db.Close()
+
+	commit := func(kvs []KeyValue, conflict ConflictPolicy) (int32, error) {
+		type result struct {
+			written int32
+			err     error
+		}
+		results := make(chan result, 1)
+		commitBatch(db, kvs, conflict, func(written int32, err error) {
+			results <- result{written, err}
+		})
+		r := <-results
+		return r.written, r.err
+	}
+
+	written, err := commit([]KeyValue{{Key: []byte("key1"), Value: []byte("value1")}}, ConflictOverwrite)
+	require.Nil(t, err)
+	require.Equal(t, int32(1), written)
+
+	// A batch whose keys are all skipped still completes
+	written, err = commit([]KeyValue{{Key: []byte("key1"), Value: []byte("other")}}, ConflictSkip)
+	require.Nil(t, err)
+	require.Equal(t, int32(0), written)
+	written, err = commit(nil, ConflictOverwrite)
+	require.Nil(t, err)
+	require.Equal(t, int32(0), written)
+
+	// A key that cannot be set or already exists fails the whole batch
+	written, err = commit([]KeyValue{{Key: []byte("key2"), Value: []byte("value2")}, {Key: []byte{}}}, ConflictOverwrite)
+	require.NotNil(t, err)
+	require.Equal(t, int32(0), written)
+	written, err = commit([]KeyValue{{Key: []byte("key3"), Value: []byte("value3")}, {Key: []byte("key1")}}, ConflictError)
+	require.EqualError(t, err, `key "key1" already exists`)
+	require.Equal(t, int32(0), written)
+
+	value, err := Get(db, []byte("key1"))
+	require.Nil(t, err)
+	require.Equal(t, []byte("value1"), value)
+	for _, key := range []string{"key2", "key3"} {
+		_, err = Get(db, []byte(key))
+		require.Equal(t, badger.ErrKeyNotFound, err)
+	}
+}